@@ -1,3 +1,12 @@
 module github.com/ShindouMihou/siopao
 
 go 1.20
+
+require (
+	github.com/cespare/xxhash/v2 v2.2.0
+	golang.org/x/crypto v0.17.0
+	golang.org/x/sys v0.15.0
+	lukechampine.com/blake3 v1.2.1
+)
+
+require github.com/klauspost/cpuid/v2 v2.0.9 // indirect
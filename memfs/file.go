@@ -0,0 +1,182 @@
+package memfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+	"time"
+)
+
+type file struct {
+	node   *node
+	fsys   *FS
+	name   string
+	flag   int
+	offset int64
+	closed bool
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	f.fsys.mu.RLock()
+	defer f.fsys.mu.RUnlock()
+	if f.closed {
+		return nil, &fs.PathError{Op: "stat", Path: f.name, Err: fs.ErrClosed}
+	}
+	return f.node.info(), nil
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if err := f.fsys.check("read", f.name); err != nil {
+		return 0, err
+	}
+	if f.closed {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrClosed}
+	}
+	if f.flag&os.O_WRONLY != 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: syscall.EBADF}
+	}
+	if f.offset >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[f.offset:])
+	f.offset += int64(n)
+	return n, nil
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if err := f.fsys.check("write", f.name); err != nil {
+		return 0, err
+	}
+	if f.closed {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrClosed}
+	}
+	if f.flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: syscall.EBADF}
+	}
+
+	f.fsys.writes++
+	if f.fsys.failWriteAt > 0 && f.fsys.writes == f.fsys.failWriteAt {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: f.fsys.failWrite}
+	}
+
+	if f.flag&os.O_APPEND != 0 {
+		f.offset = int64(len(f.node.data))
+	}
+	end := f.offset + int64(len(p))
+	if growth := end - int64(len(f.node.data)); growth > 0 {
+		if f.fsys.capacity > 0 && f.fsys.used+growth > f.fsys.capacity {
+			return 0, &fs.PathError{Op: "write", Path: f.name, Err: syscall.ENOSPC}
+		}
+		f.fsys.used += growth
+		f.node.data = append(f.node.data, make([]byte, growth)...)
+	}
+	n := copy(f.node.data[f.offset:], p)
+	f.offset += int64(n)
+	f.node.modTime = time.Now()
+	return n, nil
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if f.closed {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrClosed}
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.node.data))
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *file) Truncate(size int64) error {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if f.closed {
+		return &fs.PathError{Op: "truncate", Path: f.name, Err: fs.ErrClosed}
+	}
+	if size < 0 {
+		return &fs.PathError{Op: "truncate", Path: f.name, Err: fs.ErrInvalid}
+	}
+	growth := size - int64(len(f.node.data))
+	if growth > 0 {
+		if f.fsys.capacity > 0 && f.fsys.used+growth > f.fsys.capacity {
+			return &fs.PathError{Op: "truncate", Path: f.name, Err: syscall.ENOSPC}
+		}
+		f.node.data = append(f.node.data, make([]byte, growth)...)
+	} else {
+		f.node.data = f.node.data[:size]
+	}
+	f.fsys.used += growth
+	f.node.modTime = time.Now()
+	return nil
+}
+
+// Sync does nothing as the contents are already in memory, it exists to behave like an *os.File.
+func (f *file) Sync() error {
+	return nil
+}
+
+func (f *file) Close() error {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+	return nil
+}
+
+type dir struct {
+	node    *node
+	fsys    *FS
+	name    string
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) {
+	d.fsys.mu.RLock()
+	defer d.fsys.mu.RUnlock()
+	return d.node.info(), nil
+}
+
+func (d *dir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: syscall.EISDIR}
+}
+
+func (d *dir) ReadDir(count int) ([]fs.DirEntry, error) {
+	if d.entries == nil {
+		d.fsys.mu.RLock()
+		d.entries = entries(d.node)
+		d.fsys.mu.RUnlock()
+	}
+	remaining := d.entries[d.offset:]
+	if count <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(remaining) {
+		count = len(remaining)
+	}
+	d.offset += count
+	return remaining[:count], nil
+}
+
+func (d *dir) Close() error {
+	return nil
+}
@@ -0,0 +1,392 @@
+// Package memfs is an in-memory siopao.Filesystem, intended for tests that should not touch the disk. Every File
+// operation and streaming reader or writer is supported, and faults can be injected to test error paths.
+package memfs
+
+import (
+	"github.com/ShindouMihou/siopao/siopao"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type node struct {
+	name     string
+	mode     fs.FileMode
+	modTime  time.Time
+	data     []byte
+	children map[string]*node
+}
+
+func (n *node) isDir() bool {
+	return n.mode.IsDir()
+}
+
+func (n *node) info() fs.FileInfo {
+	return &fileInfo{name: n.name, size: int64(len(n.data)), mode: n.mode, modTime: n.modTime}
+}
+
+// FS is an in-memory filesystem, the zero value is not usable, use New instead. It is safe for concurrent use.
+//
+// Paths are handled like the paths of a siopao.Filesystem rather than io/fs, which means that both forward slashes
+// and the separator of the platform work, and that absolute paths are treated as relative to the root. Use the FS
+// method of a File opened from this filesystem for a strict io/fs.FS.
+type FS struct {
+	mu   sync.RWMutex
+	root *node
+
+	writes      int
+	failWriteAt int
+	failWrite   error
+	capacity    int64
+	used        int64
+	fault       func(op string, name string) error
+}
+
+// New creates an empty in-memory filesystem.
+func New() *FS {
+	return &FS{
+		root: &node{name: ".", mode: fs.ModeDir | 0777, modTime: time.Now(), children: make(map[string]*node)},
+	}
+}
+
+// FailWrite makes the nth write, counting from the next write, fail with the given error. This can be used to
+// test how your code handles failures in the middle of writing.
+func (fsys *FS) FailWrite(n int, err error) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	fsys.writes = 0
+	fsys.failWriteAt = n
+	fsys.failWrite = err
+}
+
+// SetCapacity limits the total amount of bytes that the files can hold, writes that go beyond the capacity fail
+// with syscall.ENOSPC, simulating a full disk. Zero, or any negative value, removes the limit.
+func (fsys *FS) SetCapacity(bytes int64) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	fsys.capacity = bytes
+}
+
+// InjectFault sets a function that is called before every operation with the name of the operation (open, stat,
+// readdir, mkdir, remove, rename, read, write) and the path, returning an error from it fails the operation with
+// that error. Pass nil to remove the fault.
+func (fsys *FS) InjectFault(fn func(op string, name string) error) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	fsys.fault = fn
+}
+
+func (fsys *FS) check(op string, name string) error {
+	if fsys.fault == nil {
+		return nil
+	}
+	if err := fsys.fault(op, name); err != nil {
+		return &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	return nil
+}
+
+func clean(name string) string {
+	name = path.Clean("/" + filepath.ToSlash(name))
+	if name == "/" {
+		return "."
+	}
+	return name[1:]
+}
+
+func split(name string) []string {
+	if name == "." {
+		return nil
+	}
+	return strings.Split(name, "/")
+}
+
+// lookup finds the node of the path, the lock must be held by the caller.
+func (fsys *FS) lookup(op string, name string) (*node, error) {
+	current := fsys.root
+	for _, part := range split(clean(name)) {
+		if !current.isDir() {
+			return nil, &fs.PathError{Op: op, Path: name, Err: syscall.ENOTDIR}
+		}
+		child, ok := current.children[part]
+		if !ok {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		current = child
+	}
+	return current, nil
+}
+
+// parent finds the node of the parent directory of the path, the lock must be held by the caller.
+func (fsys *FS) parent(op string, name string) (*node, string, error) {
+	cleaned := clean(name)
+	if cleaned == "." {
+		return nil, "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	dir, err := fsys.lookup(op, path.Dir(cleaned))
+	if err != nil {
+		return nil, "", err
+	}
+	if !dir.isDir() {
+		return nil, "", &fs.PathError{Op: op, Path: name, Err: syscall.ENOTDIR}
+	}
+	return dir, path.Base(cleaned), nil
+}
+
+func (fsys *FS) Open(name string) (fs.File, error) {
+	fsys.mu.RLock()
+	defer fsys.mu.RUnlock()
+	if err := fsys.check("open", name); err != nil {
+		return nil, err
+	}
+	n, err := fsys.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if n.isDir() {
+		return &dir{node: n, fsys: fsys, name: name}, nil
+	}
+	return &file{node: n, fsys: fsys, name: name, flag: os.O_RDONLY}, nil
+}
+
+func (fsys *FS) OpenFile(name string, flag int, perm fs.FileMode) (siopao.WritableFile, error) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if err := fsys.check("open", name); err != nil {
+		return nil, err
+	}
+
+	parent, base, err := fsys.parent("open", name)
+	if err != nil {
+		return nil, err
+	}
+	n, ok := parent.children[base]
+	switch {
+	case ok && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case ok && n.isDir():
+		return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case !ok:
+		n = &node{name: base, mode: perm & fs.ModePerm, modTime: time.Now()}
+		parent.children[base] = n
+		parent.modTime = n.modTime
+	}
+
+	if flag&os.O_TRUNC != 0 {
+		fsys.used -= int64(len(n.data))
+		n.data = nil
+		n.modTime = time.Now()
+	}
+	return &file{node: n, fsys: fsys, name: name, flag: flag}, nil
+}
+
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	fsys.mu.RLock()
+	defer fsys.mu.RUnlock()
+	if err := fsys.check("stat", name); err != nil {
+		return nil, err
+	}
+	n, err := fsys.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return n.info(), nil
+}
+
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	fsys.mu.RLock()
+	defer fsys.mu.RUnlock()
+	if err := fsys.check("readdir", name); err != nil {
+		return nil, err
+	}
+	n, err := fsys.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !n.isDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: syscall.ENOTDIR}
+	}
+	return entries(n), nil
+}
+
+func (fsys *FS) MkdirAll(name string, perm fs.FileMode) error {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if err := fsys.check("mkdir", name); err != nil {
+		return err
+	}
+
+	current := fsys.root
+	for _, part := range split(clean(name)) {
+		child, ok := current.children[part]
+		if !ok {
+			child = &node{name: part, mode: fs.ModeDir | perm&fs.ModePerm, modTime: time.Now(), children: make(map[string]*node)}
+			current.children[part] = child
+			current.modTime = child.modTime
+		} else if !child.isDir() {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: syscall.ENOTDIR}
+		}
+		current = child
+	}
+	return nil
+}
+
+func (fsys *FS) Remove(name string) error {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if err := fsys.check("remove", name); err != nil {
+		return err
+	}
+
+	parent, base, err := fsys.parent("remove", name)
+	if err != nil {
+		return err
+	}
+	n, ok := parent.children[base]
+	if !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	if n.isDir() && len(n.children) > 0 {
+		return &fs.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+	}
+	fsys.release(n)
+	delete(parent.children, base)
+	parent.modTime = time.Now()
+	return nil
+}
+
+func (fsys *FS) RemoveAll(name string) error {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if err := fsys.check("remove", name); err != nil {
+		return err
+	}
+
+	if clean(name) == "." {
+		fsys.release(fsys.root)
+		fsys.root.children = make(map[string]*node)
+		return nil
+	}
+	parent, base, err := fsys.parent("remove", name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if n, ok := parent.children[base]; ok {
+		fsys.release(n)
+		delete(parent.children, base)
+		parent.modTime = time.Now()
+	}
+	return nil
+}
+
+func (fsys *FS) Rename(oldpath, newpath string) error {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if err := fsys.check("rename", oldpath); err != nil {
+		return err
+	}
+
+	fail := func(err error) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+	oldParent, oldBase, err := fsys.parent("rename", oldpath)
+	if err != nil {
+		return fail(err)
+	}
+	n, ok := oldParent.children[oldBase]
+	if !ok {
+		return fail(fs.ErrNotExist)
+	}
+	newParent, newBase, err := fsys.parent("rename", newpath)
+	if err != nil {
+		return fail(err)
+	}
+	if n.isDir() && strings.HasPrefix(clean(newpath)+"/", clean(oldpath)+"/") {
+		return fail(fs.ErrInvalid)
+	}
+	if existing, ok := newParent.children[newBase]; ok && existing != n {
+		if existing.isDir() != n.isDir() || (existing.isDir() && len(existing.children) > 0) {
+			return fail(fs.ErrExist)
+		}
+		fsys.release(existing)
+	}
+
+	delete(oldParent.children, oldBase)
+	n.name = newBase
+	newParent.children[newBase] = n
+	now := time.Now()
+	oldParent.modTime, newParent.modTime = now, now
+	return nil
+}
+
+func (fsys *FS) Chmod(name string, mode fs.FileMode) error {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if err := fsys.check("chmod", name); err != nil {
+		return err
+	}
+	n, err := fsys.lookup("chmod", name)
+	if err != nil {
+		return err
+	}
+	n.mode = n.mode&^fs.ModePerm | mode&fs.ModePerm
+	return nil
+}
+
+func (fsys *FS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if err := fsys.check("chtimes", name); err != nil {
+		return err
+	}
+	n, err := fsys.lookup("chtimes", name)
+	if err != nil {
+		return err
+	}
+	n.modTime = mtime
+	return nil
+}
+
+// release gives back the capacity used by the node and its children, the lock must be held by the caller.
+func (fsys *FS) release(n *node) {
+	fsys.used -= int64(len(n.data))
+	for _, child := range n.children {
+		fsys.release(child)
+	}
+}
+
+func entries(n *node) []fs.DirEntry {
+	list := make([]fs.DirEntry, 0, len(n.children))
+	for _, child := range n.children {
+		list = append(list, fs.FileInfoToDirEntry(child.info()))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name() < list[j].Name()
+	})
+	return list
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (info *fileInfo) Name() string       { return info.name }
+func (info *fileInfo) Size() int64        { return info.size }
+func (info *fileInfo) Mode() fs.FileMode  { return info.mode }
+func (info *fileInfo) ModTime() time.Time { return info.modTime }
+func (info *fileInfo) IsDir() bool        { return info.mode.IsDir() }
+func (info *fileInfo) Sys() any           { return nil }
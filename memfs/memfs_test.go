@@ -0,0 +1,165 @@
+package memfs
+
+import (
+	"errors"
+	"github.com/ShindouMihou/siopao/siopao"
+	"io/fs"
+	"strings"
+	"syscall"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFS_WriteAndRead(t *testing.T) {
+	fsys := New()
+	file := siopao.OpenFS(fsys, "nested/write-01.txt")
+	if err := file.Overwrite("hello"); err != nil {
+		t.Fatal("failed to write to memory file: ", err)
+	}
+	if err := file.Write(" world"); err != nil {
+		t.Fatal("failed to append to memory file: ", err)
+	}
+	text, err := file.Text()
+	if err != nil {
+		t.Fatal("failed to read memory file: ", err)
+	}
+	if text != "hello world" {
+		t.Fatal("memory file does not match expected result, got '", text, "' instead of 'hello world'")
+	}
+
+	checksum, err := file.Checksum(siopao.Md5Checksum)
+	if err != nil {
+		t.Fatal("failed to checksum memory file: ", err)
+	}
+	if checksum != "5eb63bbbe01eeed093cb22bb8f5acdc3" {
+		t.Fatal("checksum of memory file is ", checksum)
+	}
+}
+
+func TestFS_Streaming(t *testing.T) {
+	file := siopao.OpenFS(New(), "reader-01.txt")
+	writer, err := file.Writer(true)
+	if err != nil {
+		t.Fatal("failed to open memory writer: ", err)
+	}
+	for i := 0; i < 50; i++ {
+		if err := writer.Write("hello world\n"); err != nil {
+			t.Fatal("failed to write to memory writer: ", err)
+		}
+	}
+	if err := writer.End(); err != nil {
+		t.Fatal("failed to close memory writer: ", err)
+	}
+
+	reader, err := file.TextReader()
+	if err != nil {
+		t.Fatal("failed to open memory reader: ", err)
+	}
+	count, err := reader.Count()
+	if err != nil {
+		t.Fatal("failed to read memory file: ", err)
+	}
+	if count != 50 {
+		t.Fatal("memory file has ", count, " lines instead of 50")
+	}
+}
+
+func TestFS_MoveAndRecurse(t *testing.T) {
+	fsys := New()
+	if err := siopao.OpenFS(fsys, "a/1.txt").Overwrite("hello world"); err != nil {
+		t.Fatal("failed to write to memory file: ", err)
+	}
+	if err := siopao.OpenFS(fsys, "a/1.txt").Copy("a/2.txt"); err != nil {
+		t.Fatal("failed to copy memory file: ", err)
+	}
+	if err := siopao.OpenFS(fsys, "a/2.txt").Rename("3.txt"); err != nil {
+		t.Fatal("failed to rename memory file: ", err)
+	}
+	if err := siopao.OpenFS(fsys, "a/3.txt").MoveTo("b/c"); err != nil {
+		t.Fatal("failed to move memory file: ", err)
+	}
+
+	var visited []string
+	if err := siopao.OpenFS(fsys, ".").Recurse(true, func(file *siopao.File) {
+		visited = append(visited, file.Path())
+	}); err != nil {
+		t.Fatal("failed to recurse memory filesystem: ", err)
+	}
+	if strings.Join(visited, ",") != "a,a/1.txt,b,b/c,b/c/3.txt" {
+		t.Fatal("recurse of memory filesystem visited ", visited)
+	}
+
+	if err := siopao.OpenFS(fsys, "b").Delete(); !errors.Is(err, syscall.ENOTEMPTY) {
+		t.Fatal("deleting a non-empty memory directory did not fail: ", err)
+	}
+	if err := siopao.OpenFS(fsys, "b").DeleteRecursively(); err != nil {
+		t.Fatal("failed to delete memory directory: ", err)
+	}
+	if _, err := fsys.Stat("b/c/3.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("memory directory was not deleted: ", err)
+	}
+}
+
+func TestFS_Faults(t *testing.T) {
+	fsys := New()
+	file := siopao.OpenFS(fsys, "fault-01.txt")
+
+	injected := errors.New("injected failure")
+	fsys.FailWrite(2, injected)
+	if err := file.Write("hello"); err != nil {
+		t.Fatal("first write should not fail: ", err)
+	}
+	if err := file.Write("hello"); !errors.Is(err, injected) {
+		t.Fatal("second write did not fail with the injected error: ", err)
+	}
+	fsys.FailWrite(0, nil)
+
+	fsys.SetCapacity(8)
+	if err := file.Write("hello"); !errors.Is(err, syscall.ENOSPC) {
+		t.Fatal("write beyond the capacity did not fail with ENOSPC: ", err)
+	}
+	fsys.SetCapacity(0)
+
+	fsys.InjectFault(func(op string, name string) error {
+		if op == "read" {
+			return injected
+		}
+		return nil
+	})
+	reader, err := file.Reader()
+	if err != nil {
+		t.Fatal("failed to open memory reader: ", err)
+	}
+	if err := reader.EachLine(func(line []byte) {}); !errors.Is(err, injected) {
+		t.Fatal("read did not fail with the injected error: ", err)
+	}
+}
+
+func TestFS_TestFS(t *testing.T) {
+	fsys := New()
+	for _, path := range []string{"a.txt", "nested/b.txt", "nested/deep/c.txt"} {
+		if err := siopao.OpenFS(fsys, path).Overwrite("hello world"); err != nil {
+			t.Fatal("failed to write to memory file: ", err)
+		}
+	}
+	if err := fstest.TestFS(siopao.OpenFS(fsys, ".").FS(), "a.txt", "nested/b.txt", "nested/deep/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFS_CopyFallback(t *testing.T) {
+	fsys := New()
+	if err := siopao.OpenFS(fsys, "a/1.txt").Overwrite("hello world"); err != nil {
+		t.Fatal("failed to write to memory file: ", err)
+	}
+	strategies := []siopao.CopyStrategy{siopao.ReflinkCopy, siopao.KernelCopy, siopao.SparseCopy, siopao.HardlinkCopy}
+	for i, strategy := range strategies {
+		dest := "a/copy-" + string(rune('a'+i)) + ".txt"
+		if err := siopao.OpenFS(fsys, "a/1.txt").Copy(dest, siopao.WithStrategy(strategy)); err != nil {
+			t.Fatal("failed to copy memory file: ", err)
+		}
+		if text, err := siopao.OpenFS(fsys, dest).Text(); err != nil || text != "hello world" {
+			t.Fatal("copy of memory file did not fall back to streaming: ", text, err)
+		}
+	}
+}
@@ -1,8 +1,14 @@
 package siopao
 
+import (
+	"io/fs"
+	"path/filepath"
+)
+
 type File struct {
 	path  string
 	isDir int
+	fs    Filesystem
 }
 
 // Open opens up a new interface with the given file.
@@ -11,9 +17,24 @@ type File struct {
 // closed immediately after use, unless it is needed by streaming. This prevents unnecessary resources from being
 // leaked.
 func Open(path string) *File {
+	return OpenFS(OSFilesystem{}, path)
+}
+
+// OpenFS works like Open, but opens the file from the given filesystem instead of the filesystem of the operating
+// system. When the filesystem implements Filesystem, such as memfs, all the operations are supported, otherwise, the
+// filesystem is treated as read-only, which allows reading from an embed.FS, zip archive and others.
+//
+// Paths of files opened from an io/fs.FS that does not implement Filesystem follow the rules of io/fs, which means
+// that they are unrooted and use forward slashes, with "." being the root.
+func OpenFS(fsys fs.FS, path string) *File {
+	filesystem, ok := fsys.(Filesystem)
+	if !ok {
+		filesystem = readOnlyFilesystem{fsys: fsys}
+	}
 	return &File{
 		path:  path,
 		isDir: -1,
+		fs:    filesystem,
 	}
 }
 
@@ -21,3 +42,31 @@ func Open(path string) *File {
 func (file *File) Path() string {
 	return file.path
 }
+
+// Filesystem gets the Filesystem that the file uses.
+func (file *File) Filesystem() Filesystem {
+	return file.fs
+}
+
+// Join creates a File for the path inside this file's path, using the same Filesystem.
+func (file *File) Join(elem ...string) *File {
+	return file.derive(filepath.Join(append([]string{file.path}, elem...)...))
+}
+
+// derive creates a File for another path that uses the same Filesystem.
+func (file *File) derive(path string) *File {
+	return &File{
+		path:  path,
+		isDir: -1,
+		fs:    file.fs,
+	}
+}
+
+// size gets the size of the file, this is -1 when the size cannot be known.
+func (file *File) size() int64 {
+	info, err := file.fs.Stat(file.path)
+	if err != nil {
+		return -1
+	}
+	return info.Size()
+}
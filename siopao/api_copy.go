@@ -1,55 +1,88 @@
 package siopao
 
-import (
-	"crypto/md5"
-	"crypto/sha256"
-	"crypto/sha512"
-	"encoding/hex"
-	"errors"
-	"io"
-	"os"
+import "io"
+
+type CopyStrategy uint8
+
+const (
+	// StreamCopy copies the contents through the program with io.Copy, this works with every Filesystem and is the
+	// strategy that every other strategy falls back to when it isn't supported.
+	StreamCopy CopyStrategy = iota
+	// ReflinkCopy clones the file with FICLONE on filesystems that support it, such as btrfs and XFS, which shares the
+	// contents until either file is modified. This falls back to KernelCopy when the file cannot be cloned.
+	ReflinkCopy
+	// KernelCopy copies the contents inside the kernel with copy_file_range, without passing through the program.
+	KernelCopy
+	// SparseCopy copies only the data of the file with SEEK_DATA and SEEK_HOLE, keeping the holes of sparse files,
+	// such as the images of virtual machines, instead of filling them with zeroes.
+	SparseCopy
+	// HardlinkCopy creates a hard link to the file instead of copying it, which means that both paths share the same
+	// contents, and modifying one modifies the other.
+	HardlinkCopy
 )
 
-// Copy copies the contents of the given source (file) into the destination.
-func (file *File) Copy(dest string) error {
-	destination := Open(dest)
-	_, err := write(destination, true, func(destFile *os.File) (*any, error) {
-		srcFile, err := file.openRead()
-		if err != nil {
-			return nil, err
-		}
-		defer file.close(srcFile)
-		_, err = io.Copy(destFile, srcFile)
-		return nil, err
-	})
-	return err
+// Copy copies the contents of the given source (file) into the destination. By default, this streams the contents
+// through the program, use WithStrategy to copy with reflinks, hard links, copy_file_range or while keeping the holes
+// of sparse files, these are only supported on Linux and fall back to streaming when they are not supported.
+//
+// An existing destination is replaced, use WithPolicy to fail, skip or keep both files instead.
+//
+// Copy supports the following options: WithStrategy, WithPolicy, WithProgress, WithProgressChannel and
+// WithProgressInterval.
+func (file *File) Copy(dest string, opts ...Option) error {
+	return file.wrap("copy", file.copy(dest, newOptions(opts)))
 }
 
-// CopyWithHash works similar to Copy but also creates a hash of the contents.
-func (file *File) CopyWithHash(kind ChecksumKind, dest string) (*string, error) {
-	destination := Open(dest)
-	return write(destination, true, func(destFile *os.File) (*string, error) {
-		srcFile, err := file.openRead()
-		if err != nil {
-			return nil, err
-		}
-		defer file.close(srcFile)
-		hsh := sha256.New()
-		switch kind {
-		case Sha256Checksum:
-			hsh = sha256.New()
-		case Md5Checksum:
-			hsh = md5.New()
-		case Sha512Checksum:
-			hsh = sha512.New()
-		default:
-			return nil, errors.New("unsupported checksum kind")
-		}
-		teeReader := io.TeeReader(srcFile, hsh)
-		if _, err = io.Copy(destFile, teeReader); err != nil {
-			return nil, err
-		}
-		sum := hex.EncodeToString(hsh.Sum(nil))
-		return &sum, nil
+// CopyWithHash works similar to Copy but also creates a hash of the contents, this is encoded as hexadecimal unless
+// another encoding is given with WithEncoding. The hash is nil when the copy was skipped by the OverwritePolicy.
+//
+// CopyWithHash supports the following options: WithEncoding, WithPolicy, WithProgress, WithProgressChannel and
+// WithProgressInterval.
+func (file *File) CopyWithHash(kind ChecksumKind, dest string, opts ...Option) (*string, error) {
+	sums, err := file.copyWithHashes(dest, []ChecksumKind{kind}, newOptions(opts))
+	if err != nil {
+		return nil, file.wrap("copy", err)
+	}
+	if sums == nil {
+		return nil, nil
+	}
+	sum := sums[kind]
+	return &sum, nil
+}
+
+// CopyWithHashes works similar to CopyWithHash but creates the hashes of all the given kinds at the same time, while
+// reading the source only once. The hashes are nil when the copy was skipped by the OverwritePolicy.
+//
+// CopyWithHashes supports the following options: WithEncoding, WithPolicy, WithProgress, WithProgressChannel and
+// WithProgressInterval.
+func (file *File) CopyWithHashes(dest string, kinds []ChecksumKind, opts ...Option) (map[ChecksumKind]string, error) {
+	sums, err := file.copyWithHashes(dest, kinds, newOptions(opts))
+	return sums, file.wrap("copy", err)
+}
+
+func (file *File) copyWithHashes(dest string, kinds []ChecksumKind, options *options) (map[ChecksumKind]string, error) {
+	hashes, err := newHashes(kinds)
+	if err != nil {
+		return nil, err
+	}
+	var sums map[ChecksumKind]string
+	_, err = file.place(dest, options.policy, func(destination *File) error {
+		_, err := output(destination, options.policy, func(destFile WritableFile) (*any, error) {
+			srcFile, err := file.openRead()
+			if err != nil {
+				return nil, err
+			}
+			defer file.close(srcFile)
+			tracker := options.tracker(file.size())
+			teeReader := io.TeeReader(track(srcFile, tracker), hashes)
+			if _, err = io.Copy(destFile, teeReader); err != nil {
+				return nil, err
+			}
+			tracker.Finish()
+			sums = hashes.sums(options.encoding)
+			return nil, nil
+		})
+		return err
 	})
+	return sums, err
 }
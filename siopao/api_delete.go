@@ -1,14 +1,54 @@
 package siopao
 
-import "os"
-
 // Delete deletes the file, or an empty directory. If you need to delete a directory that isn't empty, then use
 // DeleteRecursively instead.
 func (file *File) Delete() error {
-	return os.Remove(file.path)
+	return file.wrap("delete", file.fs.Remove(file.path))
 }
 
 // DeleteRecursively deletes the file or directory and its children, if there are any, simply a short-hand of os.RemoveAll.
-func (file *File) DeleteRecursively() error {
-	return os.RemoveAll(file.path)
+//
+// To protect against deleting everything because of an empty or wrong path, this fails with ErrUnsafeDelete when the
+// path is empty, the root of the filesystem, or the home directory of the user or one of its parents. WithMaxFiles
+// and WithMaxBytes can also be used to fail when the directory has more files, or bytes, than expected, in which
+// case nothing is deleted. Use DeleteRecursivelyDryRun to see what would be deleted.
+//
+// DeleteRecursively supports the following options: WithMaxFiles and WithMaxBytes.
+func (file *File) DeleteRecursively(opts ...Option) error {
+	options := newOptions(opts)
+	if err := file.guard(); err != nil {
+		return file.wrap("delete", err)
+	}
+	if options.maxFiles > 0 || options.maxBytes > 0 {
+		if _, err := file.deletable(options); err != nil {
+			return file.wrap("delete", err)
+		}
+	}
+	return file.wrap("delete", file.fs.RemoveAll(file.path))
+}
+
+// DeleteRecursivelyDryRun works like DeleteRecursively, including its guards and limits, but doesn't delete anything
+// and lists the files and directories that would be deleted instead, starting with the file itself. Symbolic links
+// are listed, but never followed, as they are not followed when deleting either.
+//
+// DeleteRecursivelyDryRun supports the following options: WithMaxFiles and WithMaxBytes.
+func (file *File) DeleteRecursivelyDryRun(opts ...Option) ([]*File, error) {
+	if err := file.guard(); err != nil {
+		return nil, file.wrap("delete", err)
+	}
+	files, err := file.deletable(newOptions(opts))
+	return files, file.wrap("delete", err)
+}
+
+// Shred overwrites the contents of the file with random bytes for the given amount of passes, at least one, before
+// deleting it, which makes the contents harder to recover than with Delete. Each pass is synced onto the disk when
+// the file supports it.
+//
+// Note that this cannot guarantee that the contents are gone on copy-on-write or journaling filesystems, SSDs with
+// wear leveling, or when there are other hard links or copies of the file.
+func (file *File) Shred(passes int) error {
+	if err := file.shred(passes); err != nil {
+		return file.wrap("shred", err)
+	}
+	return file.wrap("shred", file.fs.Remove(file.path))
 }
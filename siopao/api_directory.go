@@ -1,11 +1,22 @@
 package siopao
 
 import (
-	"fmt"
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 )
 
+// WalkFunc is the function called by Walk for each file, or directory, that is visited. Returning fs.SkipDir on a
+// directory skips its contents, while returning fs.SkipDir on a file skips the remaining files in the same
+// directory. Returning fs.SkipAll stops the walk entirely, any other error aborts the walk with that error.
+type WalkFunc func(file *File) error
+
+// WalkErrorHandler is the function called when Walk fails to read a directory, see WithErrorHandler.
+type WalkErrorHandler func(file *File, err error) error
+
 // IsDir checks whether the file is a directory, when the File comes from a Recurse call, or another call previously
 // used `IsDir` then that value will be cached. To not use the cached value, use the UncachedIsDir method instead.
 func (file *File) IsDir() (bool, error) {
@@ -24,9 +35,9 @@ func (file *File) IsDir() (bool, error) {
 // UncachedIsDir checks whether the file is a directory without passing through the cache. This is recommended
 // to use when the file is frequently changing between a directory, or a file.
 func (file *File) UncachedIsDir() (bool, error) {
-	fileInfo, err := os.Stat(file.path)
+	fileInfo, err := file.fs.Stat(file.path)
 	if err != nil {
-		return false, err
+		return false, file.wrap("stat", err)
 	}
 
 	if fileInfo.IsDir() {
@@ -40,45 +51,207 @@ func (file *File) UncachedIsDir() (bool, error) {
 
 // Recurse recurses through the directory if it's a directory. You can specify whether to recurse
 // deep into the directory by setting the nested option to true.
-func (file *File) Recurse(nested bool, fn func(file *File)) error {
-	isDirectory, err := file.IsDir()
-	if err != nil {
-		return err
+//
+// If you need to skip directories, stop early, limit the depth or continue past unreadable directories, use Walk
+// instead.
+//
+// Recurse supports the following options: WithSymlinks.
+func (file *File) Recurse(nested bool, fn func(file *File), opts ...Option) error {
+	depth := 1
+	if nested {
+		depth = 0
 	}
-	if !isDirectory {
-		return fmt.Errorf("%s is not a directory", file.path)
+	return file.Walk(func(file *File) error {
+		fn(file)
+		return nil
+	}, append(opts[:len(opts):len(opts)], WithMaxDepth(depth))...)
+}
+
+// Walk walks through the directory, calling the function for each of its files and directories in lexical
+// order, the directory itself is not passed to the function. Unlike Recurse, the function can control the walk by
+// returning fs.SkipDir or fs.SkipAll, see WalkFunc for more details.
+//
+// Walk supports the following options: WithMaxDepth, WithErrorHandler and WithSymlinks.
+func (file *File) Walk(fn WalkFunc, opts ...Option) error {
+	if err := file.requireDir(); err != nil {
+		return file.wrap("walk", err)
+	}
+	options := newOptions(opts)
+	var ancestors []fs.FileInfo
+	if options.symlinks == FollowSymlinks {
+		info, err := file.fs.Stat(file.path)
+		if err != nil {
+			return file.wrap("walk", err)
+		}
+		ancestors = append(ancestors, info)
+	}
+	if err := file.walk(1, options, ancestors, fn); err != nil && !errors.Is(err, fs.SkipAll) {
+		return file.wrap("walk", err)
 	}
-	return file.recurse(nested, fn)
+	return nil
+}
+
+// ParallelRecurse recurses deep into the directory like Recurse, but reads the directories concurrently with the
+// given amount of workers, which is much faster for big trees or slow (e.g. network) storage. When the amount of
+// workers is zero, or lower, the number of CPUs is used instead.
+//
+// The function is never called concurrently, so it is safe to use without synchronization, but the order in which
+// the files are visited is not deterministic. Similar to Walk, the function can return fs.SkipDir or fs.SkipAll to
+// control the walk, see WalkFunc for more details.
+//
+// ParallelRecurse supports the following options: WithMaxDepth and WithErrorHandler.
+func (file *File) ParallelRecurse(workers int, fn WalkFunc, opts ...Option) error {
+	if err := file.requireDir(); err != nil {
+		return file.wrap("walk", err)
+	}
+
+	options := newOptions(opts)
+	var mu sync.Mutex
+	if handler := options.errorHandler; handler != nil {
+		options.errorHandler = func(file *File, err error) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return handler(file, err)
+		}
+	}
+	// the walk is stopped under the same lock as the function, so that it is never called again afterward.
+	stopped := false
+	return file.wrap("walk", newParallelWalker(workers, options, func(child *File, entry fs.DirEntry) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if stopped {
+			return errStopped
+		}
+		err := fn(child)
+		if err != nil && !errors.Is(err, fs.SkipDir) {
+			stopped = true
+		}
+		return err
+	}).run(file))
+}
+
+// DirSize gets the total size, in bytes, of all the files inside the directory and its subdirectories. This reads the
+// directories concurrently, similar to ParallelRecurse.
+func (file *File) DirSize() (int64, error) {
+	var size atomic.Int64
+	err := file.parallel(func(child *File, entry fs.DirEntry) error {
+		if entry.IsDir() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		size.Add(info.Size())
+		return nil
+	})
+	return size.Load(), file.wrap("walk", err)
+}
+
+// CountFiles counts all the files, excluding directories, inside the directory and its subdirectories. This reads the
+// directories concurrently, similar to ParallelRecurse.
+func (file *File) CountFiles() (int64, error) {
+	var count atomic.Int64
+	err := file.parallel(func(child *File, entry fs.DirEntry) error {
+		if !entry.IsDir() {
+			count.Add(1)
+		}
+		return nil
+	})
+	return count.Load(), file.wrap("walk", err)
 }
 
 // MkdirParent creates the parent folders of the path, this also includes the current
 // path if it is a directory already.
 func (file *File) MkdirParent() error {
-	return mkparent(file.path)
+	return file.wrap("mkdir", file.mkparent(file.path))
 }
 
-func (file *File) recurse(nested bool, fn func(file *File)) error {
-	files, err := os.ReadDir(file.path)
+// walk walks through the directory, the ancestors are the directories that are currently being walked, including
+// this one, which are only needed to break cycles when following symbolic links.
+func (file *File) walk(depth int, options *options, ancestors []fs.FileInfo, fn WalkFunc) error {
+	entries, err := file.fs.ReadDir(file.path)
 	if err != nil {
-		return err
+		if options.errorHandler == nil {
+			return err
+		}
+		if err := options.errorHandler(file, err); err != nil && !errors.Is(err, fs.SkipDir) {
+			return err
+		}
+		return nil
 	}
-	for _, f := range files {
-		path := filepath.Join(file.path, f.Name())
-		child := Open(path)
+	for _, entry := range entries {
+		child := file.child(entry)
+		symlink := entry.Type()&fs.ModeSymlink != 0
+		if symlink && options.symlinks == SkipSymlinks {
+			continue
+		}
 
-		if f.IsDir() {
-			child.isDir = 1
-		} else {
-			child.isDir = 0
+		descend := entry.IsDir()
+		var info fs.FileInfo
+		if options.symlinks == FollowSymlinks {
+			if symlink {
+				// broken links, links to files and links that would cycle are reported without being followed.
+				if target, err := file.fs.Stat(child.path); err == nil && target.IsDir() && !cycles(ancestors, target) {
+					descend, info, child.isDir = true, target, 1
+				}
+			} else if descend {
+				info, _ = entry.Info()
+			}
 		}
 
-		fn(child)
-		if f.IsDir() && nested {
-			err := child.recurse(nested, fn)
-			if err != nil {
+		if err := fn(child); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				if descend {
+					continue
+				}
+				return nil
+			}
+			return err
+		}
+		if descend && (options.maxDepth <= 0 || depth < options.maxDepth) {
+			if err := child.walk(depth+1, options, append(ancestors, info), fn); err != nil {
 				return err
 			}
 		}
 	}
 	return nil
 }
+
+// cycles checks whether the directory is one of the ancestors.
+func cycles(ancestors []fs.FileInfo, dir fs.FileInfo) bool {
+	for _, ancestor := range ancestors {
+		if ancestor != nil && os.SameFile(ancestor, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func (file *File) requireDir() error {
+	isDirectory, err := file.IsDir()
+	if err != nil {
+		return err
+	}
+	if !isDirectory {
+		return ErrNotDirectory
+	}
+	return nil
+}
+
+func (file *File) parallel(visit func(child *File, entry fs.DirEntry) error) error {
+	if err := file.requireDir(); err != nil {
+		return err
+	}
+	return newParallelWalker(0, &options{}, visit).run(file)
+}
+
+func (file *File) child(entry fs.DirEntry) *File {
+	child := file.derive(filepath.Join(file.path, entry.Name()))
+	if entry.IsDir() {
+		child.isDir = 1
+	} else {
+		child.isDir = 0
+	}
+	return child
+}
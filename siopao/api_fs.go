@@ -0,0 +1,62 @@
+package siopao
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+// FS creates an io/fs.FS of the directory at the given path, see File.FS for more details.
+func FS(dir string) fs.FS {
+	return Open(dir).FS()
+}
+
+// FS creates an io/fs.FS that is rooted at the directory, using the same Filesystem as the file. This allows the
+// directory to be used with anything that accepts an io/fs.FS, such as http.FS or template.ParseFS.
+//
+// The returned io/fs.FS also implements fs.ReadDirFS, fs.ReadFileFS and fs.StatFS.
+func (file *File) FS() fs.FS {
+	return dirFS{root: file}
+}
+
+type dirFS struct {
+	root *File
+}
+
+func (dir dirFS) resolve(op string, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(dir.root.path, filepath.FromSlash(name)), nil
+}
+
+func (dir dirFS) Open(name string) (fs.File, error) {
+	path, err := dir.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return dir.root.fs.Open(path)
+}
+
+func (dir dirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	path, err := dir.resolve("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	return dir.root.fs.ReadDir(path)
+}
+
+func (dir dirFS) ReadFile(name string) ([]byte, error) {
+	path, err := dir.resolve("readfile", name)
+	if err != nil {
+		return nil, err
+	}
+	return dir.root.derive(path).Bytes()
+}
+
+func (dir dirFS) Stat(name string) (fs.FileInfo, error) {
+	path, err := dir.resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return dir.root.fs.Stat(path)
+}
@@ -2,43 +2,189 @@ package siopao
 
 import (
 	"crypto/md5"
+	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"encoding/base64"
 	"encoding/hex"
-	"errors"
+	"github.com/cespare/xxhash/v2"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
 	"hash"
+	"hash/crc32"
 	"io"
+	"io/fs"
+	"lukechampine.com/blake3"
+	"sync"
 )
 
 type ChecksumKind string
 
 const (
-	Sha512Checksum ChecksumKind = "sha512"
-	Sha256Checksum ChecksumKind = "sha256"
-	Md5Checksum    ChecksumKind = "md5"
+	Sha512Checksum     ChecksumKind = "sha512"
+	Sha256Checksum     ChecksumKind = "sha256"
+	Md5Checksum        ChecksumKind = "md5"
+	Sha1Checksum       ChecksumKind = "sha1"
+	Sha224Checksum     ChecksumKind = "sha224"
+	Sha384Checksum     ChecksumKind = "sha384"
+	Sha3_224Checksum   ChecksumKind = "sha3-224"
+	Sha3_256Checksum   ChecksumKind = "sha3-256"
+	Sha3_384Checksum   ChecksumKind = "sha3-384"
+	Sha3_512Checksum   ChecksumKind = "sha3-512"
+	Blake2b256Checksum ChecksumKind = "blake2b-256"
+	Blake2b512Checksum ChecksumKind = "blake2b-512"
+	Blake3Checksum     ChecksumKind = "blake3"
+	Crc32cChecksum     ChecksumKind = "crc32c"
+	XxHash64Checksum   ChecksumKind = "xxh64"
 )
 
-// Checksum gets the checksum hash of the file's contents.
-func (file *File) Checksum(kind ChecksumKind) (string, error) {
-	f, err := file.openRead()
+type ChecksumEncoding uint8
+
+const (
+	// HexEncoding encodes the checksum as lowercase hexadecimal, such as the output of sha256sum.
+	HexEncoding ChecksumEncoding = iota
+	// Base64Encoding encodes the checksum as standard base64.
+	Base64Encoding
+	// RawEncoding keeps the checksum as the raw bytes of the digest.
+	RawEncoding
+	// SRIEncoding encodes the checksum in the format of Subresource Integrity, such as "sha256-<base64>".
+	SRIEncoding
+)
+
+// Encode encodes the digest of the ChecksumKind with the encoding.
+func (encoding ChecksumEncoding) Encode(kind ChecksumKind, digest []byte) string {
+	switch encoding {
+	case Base64Encoding:
+		return base64.StdEncoding.EncodeToString(digest)
+	case RawEncoding:
+		return string(digest)
+	case SRIEncoding:
+		return string(kind) + "-" + base64.StdEncoding.EncodeToString(digest)
+	default:
+		return hex.EncodeToString(digest)
+	}
+}
+
+var checksums = struct {
+	sync.RWMutex
+	factories map[ChecksumKind]func() hash.Hash
+}{
+	factories: map[ChecksumKind]func() hash.Hash{
+		Sha512Checksum:   sha512.New,
+		Sha256Checksum:   sha256.New,
+		Md5Checksum:      md5.New,
+		Sha1Checksum:     sha1.New,
+		Sha224Checksum:   sha256.New224,
+		Sha384Checksum:   sha512.New384,
+		Sha3_224Checksum: sha3.New224,
+		Sha3_256Checksum: sha3.New256,
+		Sha3_384Checksum: sha3.New384,
+		Sha3_512Checksum: sha3.New512,
+		Blake2b256Checksum: func() hash.Hash {
+			// blake2b only errors when the key is too long, there is no key here.
+			hsh, _ := blake2b.New256(nil)
+			return hsh
+		},
+		Blake2b512Checksum: func() hash.Hash {
+			hsh, _ := blake2b.New512(nil)
+			return hsh
+		},
+		Blake3Checksum: func() hash.Hash {
+			return blake3.New(32, nil)
+		},
+		Crc32cChecksum: func() hash.Hash {
+			return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+		},
+		XxHash64Checksum: func() hash.Hash {
+			return xxhash.New()
+		},
+	},
+}
+
+// RegisterChecksum registers the factory of the ChecksumKind, replacing any factory that was registered before. This
+// allows Checksum, CopyWithHash and the other checksum methods to support algorithms that siopao does not include.
+func RegisterChecksum(kind ChecksumKind, factory func() hash.Hash) {
+	checksums.Lock()
+	defer checksums.Unlock()
+	checksums.factories[kind] = factory
+}
+
+// Checksum gets the checksum hash of the file's contents, this is encoded as hexadecimal unless another encoding
+// is given with WithEncoding.
+//
+// Checksum supports the following options: WithEncoding, WithProgress, WithProgressChannel and WithProgressInterval.
+func (file *File) Checksum(kind ChecksumKind, opts ...Option) (string, error) {
+	sum, err := file.checksum(kind, newOptions(opts))
+	return sum, file.wrap("checksum", err)
+}
+
+// Checksums gets the checksums of the file's contents for all the given kinds while reading the file only once,
+// which is much faster than calling Checksum for each kind on big files. The checksums are encoded as hexadecimal.
+func (file *File) Checksums(kinds ...ChecksumKind) (map[ChecksumKind]string, error) {
+	sums, err := file.checksums(kinds, &options{})
+	return sums, file.wrap("checksum", err)
+}
+
+func (file *File) checksum(kind ChecksumKind, options *options) (string, error) {
+	sums, err := file.checksums([]ChecksumKind{kind}, options)
 	if err != nil {
 		return "", err
 	}
-	defer file.close(f)
-
-	var hsh hash.Hash
-	switch kind {
-	case Sha256Checksum:
-		hsh = sha256.New()
-	case Md5Checksum:
-		hsh = md5.New()
-	case Sha512Checksum:
-		hsh = sha512.New()
-	default:
-		return "", errors.New("unsupported checksum kind")
+	return sums[kind], nil
+}
+
+func (file *File) checksums(kinds []ChecksumKind, options *options) (map[ChecksumKind]string, error) {
+	hashes, err := newHashes(kinds)
+	if err != nil {
+		return nil, err
 	}
-	if _, err := io.Copy(hsh, f); err != nil {
-		return "", err
+	return read(file, func(f fs.File) (map[ChecksumKind]string, error) {
+		tracker := options.tracker(file.size())
+		if _, err := io.Copy(hashes, track(f, tracker)); err != nil {
+			return nil, err
+		}
+		tracker.Finish()
+		return hashes.sums(options.encoding), nil
+	})
+}
+
+// hashes is a set of hashes that are written to at the same time, allowing multiple checksums in a single pass.
+type hashes map[ChecksumKind]hash.Hash
+
+func newHashes(kinds []ChecksumKind) (hashes, error) {
+	set := make(hashes, len(kinds))
+	for _, kind := range kinds {
+		hsh, err := newHash(kind)
+		if err != nil {
+			return nil, err
+		}
+		set[kind] = hsh
+	}
+	return set, nil
+}
+
+func (set hashes) Write(p []byte) (int, error) {
+	for _, hsh := range set {
+		// hash.Hash never returns an error on Write.
+		_, _ = hsh.Write(p)
+	}
+	return len(p), nil
+}
+
+func (set hashes) sums(encoding ChecksumEncoding) map[ChecksumKind]string {
+	sums := make(map[ChecksumKind]string, len(set))
+	for kind, hsh := range set {
+		sums[kind] = encoding.Encode(kind, hsh.Sum(nil))
+	}
+	return sums
+}
+
+func newHash(kind ChecksumKind) (hash.Hash, error) {
+	checksums.RLock()
+	factory, ok := checksums.factories[kind]
+	checksums.RUnlock()
+	if !ok {
+		return nil, ErrUnsupportedChecksum
 	}
-	return hex.EncodeToString(hsh.Sum(nil)), nil
+	return factory(), nil
 }
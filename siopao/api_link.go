@@ -0,0 +1,153 @@
+package siopao
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+type SymlinkPolicy uint8
+
+const (
+	// ReportSymlinks passes symbolic links to the function like any other file without following them, this is the
+	// default.
+	ReportSymlinks SymlinkPolicy = iota
+	// SkipSymlinks doesn't pass symbolic links to the function at all.
+	SkipSymlinks
+	// FollowSymlinks passes symbolic links to the function and walks into the directories that they point to, a link
+	// that points to one of the directories that is currently being walked is not followed, which breaks cycles.
+	FollowSymlinks
+)
+
+// Symlink creates the file as a symbolic link that points to the target, the target is stored as-is, which means
+// that a relative target is relative to the folder of the link rather than the current working directory.
+func (file *File) Symlink(target string) error {
+	links, err := file.links()
+	if err != nil {
+		return file.wrap("symlink", err)
+	}
+	if err := file.mkparent(file.path); err != nil {
+		return file.wrap("symlink", err)
+	}
+	return file.wrap("symlink", links.Symlink(target, file.path))
+}
+
+// Hardlink creates the file as a hard link of the target, both paths then share the same contents, and modifying
+// one modifies the other. The target must be on the same device.
+func (file *File) Hardlink(target string) error {
+	links, err := file.links()
+	if err != nil {
+		return file.wrap("link", err)
+	}
+	if err := file.mkparent(file.path); err != nil {
+		return file.wrap("link", err)
+	}
+	return file.wrap("link", links.Link(target, file.path))
+}
+
+// Readlink gets the target of the symbolic link, as-is, without resolving it.
+func (file *File) Readlink() (string, error) {
+	links, err := file.links()
+	if err != nil {
+		return "", file.wrap("readlink", err)
+	}
+	target, err := links.Readlink(file.path)
+	return target, file.wrap("readlink", err)
+}
+
+// IsSymlink checks whether the file is a symbolic link, this is always false when the Filesystem doesn't support
+// links. Unlike IsDir, this is never cached.
+func (file *File) IsSymlink() (bool, error) {
+	links, ok := file.fs.(LinkFilesystem)
+	if !ok {
+		return false, nil
+	}
+	info, err := links.Lstat(file.path)
+	if err != nil {
+		return false, file.wrap("lstat", err)
+	}
+	return info.Mode()&fs.ModeSymlink != 0, nil
+}
+
+// Resolve creates a File of the real path of the file, with every symbolic link resolved, similar to realpath. For
+// the OSFilesystem, the path is absolute, while for the Root, the path is still relative to the root.
+func (file *File) Resolve() (*File, error) {
+	path, err := file.realpath()
+	if err != nil {
+		return nil, file.wrap("resolve", err)
+	}
+	return file.derive(path), nil
+}
+
+func (file *File) links() (LinkFilesystem, error) {
+	links, ok := file.fs.(LinkFilesystem)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	return links, nil
+}
+
+func (file *File) realpath() (string, error) {
+	if root, ok := file.fs.(*rootFilesystem); ok {
+		return root.realpath(file.path)
+	}
+	links, ok := file.fs.(LinkFilesystem)
+	if !ok {
+		if _, err := file.fs.Stat(file.path); err != nil {
+			return "", err
+		}
+		return filepath.Clean(file.path), nil
+	}
+
+	path := file.path
+	if _, ok := file.fs.(OSFilesystem); ok {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return "", err
+		}
+		path = abs
+	}
+
+	current := ""
+	if filepath.IsAbs(path) {
+		current = filepath.VolumeName(path) + string(filepath.Separator)
+	}
+	parts := strings.Split(filepath.Clean(path[len(current):]), string(filepath.Separator))
+	seen := 0
+	for len(parts) > 0 {
+		part := parts[0]
+		parts = parts[1:]
+		if part == "" || part == "." {
+			continue
+		}
+
+		next := filepath.Join(current, part)
+		info, err := links.Lstat(next)
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+
+		seen++
+		if seen > 255 {
+			return "", &fs.PathError{Op: "resolve", Path: file.path, Err: syscall.ELOOP}
+		}
+		target, err := links.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(target) {
+			current = filepath.VolumeName(target) + string(filepath.Separator)
+			target = target[len(current):]
+		}
+		parts = append(strings.Split(filepath.Clean(target), string(filepath.Separator)), parts...)
+	}
+	if current == "" {
+		return ".", nil
+	}
+	return current, nil
+}
@@ -0,0 +1,236 @@
+package siopao
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type ManifestStatus uint8
+
+const (
+	// ManifestOK is when the checksum of the file matches the manifest.
+	ManifestOK ManifestStatus = iota
+	// ManifestMismatch is when the checksum of the file does not match the manifest.
+	ManifestMismatch
+	// ManifestMissing is when the file is in the manifest, but not in the directory.
+	ManifestMissing
+	// ManifestExtra is when the file is in the directory, but not in the manifest.
+	ManifestExtra
+)
+
+// String gets the name of the ManifestStatus.
+func (status ManifestStatus) String() string {
+	switch status {
+	case ManifestOK:
+		return "ok"
+	case ManifestMismatch:
+		return "mismatch"
+	case ManifestMissing:
+		return "missing"
+	case ManifestExtra:
+		return "extra"
+	default:
+		return "unknown"
+	}
+}
+
+type ManifestResult struct {
+	// Path is the path of the file relative to the directory, using forward slashes.
+	Path   string
+	Status ManifestStatus
+	// Expected is the checksum in the manifest, this is empty for ManifestExtra.
+	Expected string
+	// Actual is the checksum of the file, this is empty for ManifestMissing and ManifestExtra.
+	Actual string
+}
+
+// manifestKinds are the checksum kinds that can be detected from the length of a manifest's checksums, which are
+// the ones that have a coreutils tool such as sha256sum.
+var manifestKinds = map[int]ChecksumKind{
+	32:  Md5Checksum,
+	40:  Sha1Checksum,
+	56:  Sha224Checksum,
+	64:  Sha256Checksum,
+	96:  Sha384Checksum,
+	128: Sha512Checksum,
+}
+
+// Verify checks whether the checksum of the file's contents matches the expected checksum, which can be encoded as
+// hexadecimal (in any case), base64 or SRI.
+func (file *File) Verify(kind ChecksumKind, expected string) (bool, error) {
+	digest, err := file.checksum(kind, &options{encoding: RawEncoding})
+	if err != nil {
+		return false, file.wrap("verify", err)
+	}
+	expected = strings.TrimSpace(expected)
+	for _, encoding := range []ChecksumEncoding{Base64Encoding, SRIEncoding} {
+		if encoding.Encode(kind, []byte(digest)) == expected {
+			return true, nil
+		}
+	}
+	return strings.EqualFold(HexEncoding.Encode(kind, []byte(digest)), expected), nil
+}
+
+// WriteManifest writes the checksums of all the files inside the directory and its subdirectories into the destination,
+// in the format of sha256sum and the other coreutils tools, which means that the manifest can also be verified with
+// "sha256sum -c" from within the directory. The destination is excluded when it is inside the directory.
+func (file *File) WriteManifest(kind ChecksumKind, dest string) error {
+	manifest := file.derive(dest)
+	var builder strings.Builder
+	err := file.Walk(func(child *File) error {
+		if !listed(child, manifest) {
+			return nil
+		}
+		sum, err := child.checksum(kind, &options{})
+		if err != nil {
+			return err
+		}
+		name, err := file.relative(child)
+		if err != nil {
+			return err
+		}
+		if strings.ContainsAny(name, "\\\n") {
+			name = strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(name)
+			builder.WriteString("\\")
+		}
+		builder.WriteString(sum + "  " + name + "\n")
+		return nil
+	})
+	if err != nil {
+		return file.wrap("manifest", err)
+	}
+	return file.wrap("manifest", manifest.Overwrite(builder.String()))
+}
+
+// VerifyManifest verifies the files inside the directory against the manifest at the given path, such as one created
+// by WriteManifest or sha256sum. The checksum kind is detected from the length of the checksums, which supports the
+// manifests of md5sum, sha1sum, sha224sum, sha256sum, sha384sum and sha512sum, for the manifests of any other kind,
+// such as blake3, whose checksums have the same length as one of these, use VerifyManifestWith instead.
+//
+// The results are sorted by their path and include the files that are not in the manifest as ManifestExtra, excluding
+// the manifest itself. Paths in the manifest that are absolute, or lead outside the directory, fail the verification.
+func (file *File) VerifyManifest(path string) ([]ManifestResult, error) {
+	results, err := file.verifyManifest("", path)
+	return results, file.wrap("manifest", err)
+}
+
+// VerifyManifestWith works like VerifyManifest, but verifies the checksums with the given kind instead of detecting
+// it, which supports manifests written by WriteManifest with any kind.
+func (file *File) VerifyManifestWith(kind ChecksumKind, path string) ([]ManifestResult, error) {
+	results, err := file.verifyManifest(kind, path)
+	return results, file.wrap("manifest", err)
+}
+
+func (file *File) verifyManifest(kind ChecksumKind, path string) ([]ManifestResult, error) {
+	manifest := file.derive(path)
+	text, err := manifest.Text()
+	if err != nil {
+		return nil, err
+	}
+
+	expected := make(map[string]string)
+	detect := kind == ""
+	for number, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		escaped := strings.HasPrefix(line, "\\")
+		line = strings.TrimPrefix(line, "\\")
+		sum, name, ok := strings.Cut(line, " ")
+		if !ok || len(name) < 2 {
+			return nil, fmt.Errorf("invalid manifest line %d", number+1)
+		}
+		// the character after the checksum is the mode, a space for text and an asterisk for binary.
+		name = name[1:]
+		if escaped {
+			name = strings.NewReplacer("\\\\", "\\", "\\n", "\n").Replace(name)
+		}
+		if detect {
+			lineKind, ok := manifestKinds[len(sum)]
+			if !ok || (kind != "" && kind != lineKind) {
+				return nil, fmt.Errorf("%w in manifest line %d", ErrUnsupportedChecksum, number+1)
+			}
+			kind = lineKind
+		}
+		name = filepath.Clean(filepath.FromSlash(name))
+		if !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("%w: path %q in manifest line %d is outside the directory", fs.ErrInvalid, name, number+1)
+		}
+		expected[filepath.ToSlash(name)] = strings.ToLower(sum)
+	}
+
+	var results []ManifestResult
+	for name, sum := range expected {
+		actual, err := file.Join(filepath.FromSlash(name)).checksum(kind, &options{})
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			results = append(results, ManifestResult{Path: name, Status: ManifestMissing, Expected: sum})
+		case err != nil:
+			return nil, err
+		case actual == sum:
+			results = append(results, ManifestResult{Path: name, Status: ManifestOK, Expected: sum, Actual: actual})
+		default:
+			results = append(results, ManifestResult{Path: name, Status: ManifestMismatch, Expected: sum, Actual: actual})
+		}
+	}
+
+	err = file.Walk(func(child *File) error {
+		if !listed(child, manifest) {
+			return nil
+		}
+		name, err := file.relative(child)
+		if err != nil {
+			return err
+		}
+		if _, ok := expected[name]; !ok {
+			results = append(results, ManifestResult{Path: name, Status: ManifestExtra})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Path < results[j].Path
+	})
+	return results, nil
+}
+
+// listed checks whether the child belongs in the manifest, which excludes the directories, including symbolic links
+// to directories, and the manifest itself.
+func listed(child *File, manifest *File) bool {
+	if child.isDir == 1 {
+		return false
+	}
+	if info, err := child.fs.Stat(child.path); err == nil && info.IsDir() {
+		return false
+	}
+	return !samePath(child.path, manifest.path)
+}
+
+// samePath checks whether both paths lead to the same place, even when one is absolute and the other is relative.
+func samePath(a, b string) bool {
+	absA, err := filepath.Abs(a)
+	if err != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	absB, err := filepath.Abs(b)
+	if err != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
+
+// relative gets the path of the child relative to the file, using forward slashes.
+func (file *File) relative(child *File) (string, error) {
+	rel, err := filepath.Rel(file.path, child.path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
@@ -1,18 +1,23 @@
 package siopao
 
 import (
-	"os"
 	"path/filepath"
 )
 
 // Move renames, or moves the file to another path. This is a more direct approach, and will be able to
 // move the file to another folder. If you want to simply rename the file's name, use Rename instead, otherwise,
 // if you want to keep the name, but move the folder, use MoveTo instead.
-func (file *File) Move(dest string) error {
-	if err := mkparent(dest); err != nil {
-		return err
-	}
-	return os.Rename(file.path, dest)
+//
+// When the destination is on another device, the file, or the whole directory, is copied to the destination,
+// verified and then deleted, keeping the permissions and timestamps when the Filesystem is an AttributeFilesystem.
+// This is not atomic, therefore, use RequireAtomic to fail instead.
+//
+// An existing destination is replaced, use WithPolicy to fail, skip or keep both files instead. Note that, unlike
+// Copy, the destination is checked before the rename, so a destination created in between is still replaced.
+//
+// Move supports the following options: RequireAtomic and WithPolicy.
+func (file *File) Move(dest string, opts ...Option) error {
+	return file.wrap("move", file.move(dest, newOptions(opts)))
 }
 
 // Rename renames the file while keeping the source folder, this is useful when you simply want to rename the
@@ -20,21 +25,21 @@ func (file *File) Move(dest string) error {
 //
 // If you want to move the file into an entirely new folder, use Move instead.
 // You can also use MoveTo if you want to move to another folder, but still keep the name.
-func (file *File) Rename(name string) error {
+//
+// Rename supports the following options: WithPolicy.
+func (file *File) Rename(name string, opts ...Option) error {
 	dir := filepath.Dir(file.path)
-	return os.Rename(file.path, filepath.Join(dir, name))
+	return file.wrap("rename", file.move(filepath.Join(dir, name), newOptions(opts)))
 }
 
 // MoveTo moves the file to another folder while keeping its name, this is useful when you just want to change
-// the folder of the file.
+// the folder of the file. Similar to Move, this falls back to copying when the folder is on another device.
 //
 // If you want to move the file into an entirely new folder, use Move instead.
 // You can also use Rename if you want to rename the file's name.
-func (file *File) MoveTo(dir string) error {
+//
+// MoveTo supports the following options: RequireAtomic and WithPolicy.
+func (file *File) MoveTo(dir string, opts ...Option) error {
 	base := filepath.Base(file.path)
-	dest := filepath.Join(dir, base)
-	if err := mkparent(dest); err != nil {
-		return err
-	}
-	return os.Rename(file.path, dest)
+	return file.wrap("move", file.move(filepath.Join(dir, base), newOptions(opts)))
 }
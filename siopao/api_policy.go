@@ -0,0 +1,18 @@
+package siopao
+
+type OverwritePolicy uint8
+
+const (
+	// ReplaceExisting replaces the destination when it exists, this is the default.
+	ReplaceExisting OverwritePolicy = iota
+	// FailIfExists fails with ErrExists when the destination exists.
+	FailIfExists
+	// SkipExisting leaves the destination, and the source, untouched when the destination exists without failing.
+	SkipExisting
+	// KeepBoth keeps the destination when it exists and uses the first free name with a numbered suffix instead, such
+	// as "report (1).txt", then "report (2).txt" and so on.
+	KeepBoth
+	// ReplaceIfNewer only replaces the destination when the source was modified after it, otherwise, this is
+	// similar to SkipExisting.
+	ReplaceIfNewer
+)
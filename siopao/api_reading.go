@@ -1,10 +1,9 @@
 package siopao
 
 import (
-	"errors"
 	"github.com/ShindouMihou/siopao/paopao"
 	"io"
-	"os"
+	"io/fs"
 	"reflect"
 )
 
@@ -21,36 +20,25 @@ func (file *File) Text() (string, error) {
 // Bytes reads the file directly as a byte array, this is not recommend to use when handling big
 // files, we recommend using Reader to stream big files instead.
 func (file *File) Bytes() ([]byte, error) {
-	bytes, err := read(file, func(f *os.File) (*[]byte, error) {
-		bytes, err := io.ReadAll(f)
-		if err != nil {
-			return nil, nil
-		}
-		return &bytes, nil
-	})
+	bytes, err := file.readAll()
 	if err != nil {
-		return nil, err
+		return nil, file.wrap("read", err)
 	}
-	return *bytes, nil
+	return bytes, nil
 }
 
 // Unmarshal unmarshals the given contents of the file with the given unmarshaler.
 func (file *File) Unmarshal(unmarshal paopao.Unmarshaler, t interface{}) error {
-	if reflect.TypeOf(t).Kind() != reflect.Pointer {
-		return errors.New("non-pointer kind for value")
+	if t == nil || reflect.TypeOf(t).Kind() != reflect.Pointer {
+		return file.wrap("unmarshal", ErrNonPointer)
 	}
 
-	if _, err := read[any](file, func(f *os.File) (*any, error) {
-		bytes, err := io.ReadAll(f)
-		if err != nil {
-			return nil, err
-		}
-		if err := unmarshal(bytes, t); err != nil {
-			return nil, err
-		}
-		return nil, nil
-	}); err != nil {
-		return err
+	bytes, err := file.readAll()
+	if err != nil {
+		return file.wrap("read", err)
+	}
+	if err := unmarshal(bytes, t); err != nil {
+		return file.wrap("unmarshal", err)
 	}
 	return nil
 }
@@ -59,3 +47,9 @@ func (file *File) Unmarshal(unmarshal paopao.Unmarshaler, t interface{}) error {
 func (file *File) Json(t interface{}) error {
 	return file.Unmarshal(paopao.Unmarshal, t)
 }
+
+func (file *File) readAll() ([]byte, error) {
+	return read(file, func(f fs.File) ([]byte, error) {
+		return io.ReadAll(f)
+	})
+}
@@ -0,0 +1,127 @@
+package siopao
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"github.com/ShindouMihou/siopao/paopao"
+	"io"
+	"io/fs"
+	"time"
+)
+
+type checksumState struct {
+	Kind    ChecksumKind `json:"kind"`
+	Offset  int64        `json:"offset"`
+	Size    int64        `json:"size"`
+	ModTime time.Time    `json:"mod_time"`
+	State   []byte       `json:"state"`
+}
+
+// ResumableChecksum works like Checksum, but periodically saves the state of the hash and how much of the file was
+// hashed into the given state file, allowing the checksum to continue from where it left off when it is interrupted,
+// which is useful for huge files. The state is saved every 64 MiB by default, which can be changed with
+// WithCheckpoint, and is removed once the checksum is complete. When the file changed since the state was saved, the
+// checksum starts over.
+//
+// Only the kinds whose hash implements encoding.BinaryMarshaler can be resumed, which includes md5, sha1, the sha2
+// family, blake2b, crc32c and xxh64, others fail with ErrUnsupportedChecksum.
+//
+// ResumableChecksum supports the following options: WithEncoding, WithProgress, WithProgressChannel,
+// WithProgressInterval and WithCheckpoint.
+func (file *File) ResumableChecksum(kind ChecksumKind, state string, opts ...Option) (string, error) {
+	sum, err := file.resumableChecksum(kind, file.derive(state), newOptions(opts))
+	return sum, file.wrap("checksum", err)
+}
+
+func (file *File) resumableChecksum(kind ChecksumKind, sidecar *File, options *options) (string, error) {
+	hsh, err := newHash(kind)
+	if err != nil {
+		return "", err
+	}
+	marshaler, ok := hsh.(encoding.BinaryMarshaler)
+	unmarshaler, ok2 := hsh.(encoding.BinaryUnmarshaler)
+	if !ok || !ok2 {
+		return "", fmt.Errorf("%w: %s cannot be resumed", ErrUnsupportedChecksum, kind)
+	}
+
+	info, err := file.fs.Stat(file.path)
+	if err != nil {
+		return "", err
+	}
+	current := checksumState{Kind: kind, Size: info.Size(), ModTime: info.ModTime()}
+
+	// a state that cannot be decoded, such as one that was cut short by a crash, is treated as if there was none.
+	var saved checksumState
+	contents, err := sidecar.Bytes()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	if err == nil && paopao.Unmarshal(contents, &saved) == nil && saved.Kind == kind && saved.Size == current.Size &&
+		saved.ModTime.Equal(current.ModTime) && saved.Offset <= saved.Size {
+		if err := unmarshaler.UnmarshalBinary(saved.State); err == nil {
+			current.Offset = saved.Offset
+		} else {
+			hsh.Reset()
+		}
+	}
+
+	return read(file, func(f fs.File) (string, error) {
+		if err := skip(f, current.Offset); err != nil {
+			return "", err
+		}
+		tracker := options.tracker(current.Size).From(current.Offset)
+		for {
+			n, err := io.CopyN(hsh, track(f, tracker), options.checkpoint)
+			current.Offset += n
+			if err != nil && err != io.EOF {
+				return "", err
+			}
+			if err == io.EOF || current.Offset >= current.Size {
+				break
+			}
+			if current.State, err = marshaler.MarshalBinary(); err != nil {
+				return "", err
+			}
+			if err := save(sidecar, current); err != nil {
+				return "", err
+			}
+		}
+		tracker.Finish()
+		if err := sidecar.fs.Remove(sidecar.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		return options.encoding.Encode(kind, hsh.Sum(nil)), nil
+	})
+}
+
+// save writes the state into a temporary file that is synced and then renamed over the sidecar, so that a crash in
+// the middle leaves either the previous state, or the new one.
+func save(sidecar *File, state checksumState) error {
+	contents, err := paopao.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return replace(sidecar, ReplaceExisting, func(temp *File) error {
+		_, err := write(temp, true, func(f WritableFile) (*any, error) {
+			if _, err := f.Write(contents); err != nil {
+				return nil, err
+			}
+			return nil, fsync(f)
+		})
+		return err
+	})
+}
+
+// skip moves the file forward by the given amount of bytes, seeking when the file supports it.
+func skip(f fs.File, offset int64) error {
+	if offset == 0 {
+		return nil
+	}
+	if seeker, ok := f.(io.Seeker); ok {
+		_, err := seeker.Seek(offset, io.SeekStart)
+		return err
+	}
+	_, err := io.CopyN(io.Discard, f, offset)
+	return err
+}
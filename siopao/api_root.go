@@ -0,0 +1,298 @@
+package siopao
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Root creates a File of the given directory whose every operation, and the operations of every File derived from it
+// through Join, Recurse, Walk, Copy, Move and others, is confined to the directory. Paths are relative to the root,
+// which means that the returned File has the path "." and user-supplied paths can be passed to Join safely, any
+// operation that would leave the root fails with ErrEscapesRoot.
+//
+// Symbolic links are only followed when they point inside the root. On Linux 5.6 and newer, files are opened with
+// openat2 relative to the directory of the root, which lets the kernel confine the resolution of the path, and cannot
+// be raced. Every other operation, such as Stat, ReadDir, Rename, Remove, Chmod and the operations on links, as well
+// as opening files on other platforms, resolves the symbolic links in siopao before the operation, which cannot protect
+// against the directory being changed by another process between the resolution and the operation itself, so the root
+// should not be writable by untrusted processes.
+func Root(dir string) (*File, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	base, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(base)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &OpError{Op: "root", Path: dir, Err: ErrNotDirectory}
+	}
+	held, err := hold(base)
+	if err != nil {
+		return nil, err
+	}
+	file := OpenFS(&rootFilesystem{base: base, dir: held}, ".")
+	file.isDir = 1
+	return file, nil
+}
+
+type rootFilesystem struct {
+	base string
+	// dir is the directory of the root, which is held open for openat2 on Linux, this is nil on other platforms.
+	dir *os.File
+}
+
+// resolve translates the path into a path on the operating system, resolving the symbolic links along the way. When
+// follow is false, the last element of the path is not resolved, which is needed for operations on the link itself.
+func (root *rootFilesystem) resolve(op string, name string, follow bool) (string, error) {
+	escape := &fs.PathError{Op: op, Path: name, Err: ErrEscapesRoot}
+	if filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", escape
+	}
+
+	parts := strings.Split(filepath.Clean(name), string(filepath.Separator))
+	current := root.base
+	links := 0
+	for len(parts) > 0 {
+		part := parts[0]
+		parts = parts[1:]
+
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if current == root.base {
+				return "", escape
+			}
+			current = filepath.Dir(current)
+			continue
+		}
+
+		next := filepath.Join(current, part)
+		if len(parts) == 0 && !follow {
+			current = next
+			break
+		}
+		info, err := os.Lstat(next)
+		if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+
+		links++
+		if links > 255 {
+			return "", &fs.PathError{Op: op, Path: name, Err: syscall.ELOOP}
+		}
+		target, err := os.Readlink(next)
+		if err != nil {
+			return "", &fs.PathError{Op: op, Path: name, Err: err}
+		}
+		if filepath.IsAbs(target) {
+			rel, err := filepath.Rel(root.base, target)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return "", escape
+			}
+			current = root.base
+			target = rel
+		}
+		parts = append(strings.Split(filepath.Clean(target), string(filepath.Separator)), parts...)
+	}
+	return current, nil
+}
+
+// hide replaces the resolved path in the error with the path that was given to the filesystem, so that the location
+// of the root is not leaked through the errors.
+func (root *rootFilesystem) hide(err error, name string) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return &fs.PathError{Op: pathErr.Op, Path: name, Err: pathErr.Err}
+	}
+	return err
+}
+
+// openResolved opens the file at the path that resolve translates the name into, which is used wherever openat2
+// isn't available.
+func (root *rootFilesystem) openResolved(name string, flag int, perm fs.FileMode) (*os.File, error) {
+	path, err := root.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, flag, perm)
+	if err != nil {
+		return nil, root.hide(err, name)
+	}
+	return f, nil
+}
+
+func (root *rootFilesystem) Open(name string) (fs.File, error) {
+	f, err := root.open(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (root *rootFilesystem) OpenFile(name string, flag int, perm fs.FileMode) (WritableFile, error) {
+	f, err := root.open(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (root *rootFilesystem) Stat(name string) (fs.FileInfo, error) {
+	path, err := root.resolve("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	return info, root.hide(err, name)
+}
+
+func (root *rootFilesystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	path, err := root.resolve("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(path)
+	return entries, root.hide(err, name)
+}
+
+func (root *rootFilesystem) MkdirAll(name string, perm fs.FileMode) error {
+	path, err := root.resolve("mkdir", name, true)
+	if err != nil {
+		return err
+	}
+	return root.hide(os.MkdirAll(path, perm), name)
+}
+
+func (root *rootFilesystem) Remove(name string) error {
+	path, err := root.resolve("remove", name, false)
+	if err != nil {
+		return err
+	}
+	if path == root.base {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+	}
+	return root.hide(os.Remove(path), name)
+}
+
+func (root *rootFilesystem) RemoveAll(name string) error {
+	path, err := root.resolve("remove", name, false)
+	if err != nil {
+		return err
+	}
+	if path == root.base {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+	}
+	return root.hide(os.RemoveAll(path), name)
+}
+
+func (root *rootFilesystem) Rename(oldpath, newpath string) error {
+	oldResolved, err := root.resolve("rename", oldpath, false)
+	if err != nil {
+		return err
+	}
+	newResolved, err := root.resolve("rename", newpath, false)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(oldResolved, newResolved); err != nil {
+		var linkErr *os.LinkError
+		if errors.As(err, &linkErr) {
+			return &os.LinkError{Op: linkErr.Op, Old: oldpath, New: newpath, Err: linkErr.Err}
+		}
+		return err
+	}
+	return nil
+}
+
+func (root *rootFilesystem) Chmod(name string, mode fs.FileMode) error {
+	path, err := root.resolve("chmod", name, true)
+	if err != nil {
+		return err
+	}
+	return root.hide(os.Chmod(path, mode), name)
+}
+
+func (root *rootFilesystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	path, err := root.resolve("chtimes", name, true)
+	if err != nil {
+		return err
+	}
+	return root.hide(os.Chtimes(path, atime, mtime), name)
+}
+
+func (root *rootFilesystem) Link(oldname, newname string) error {
+	oldResolved, err := root.resolve("link", oldname, false)
+	if err != nil {
+		return err
+	}
+	newResolved, err := root.resolve("link", newname, false)
+	if err != nil {
+		return err
+	}
+	if err := os.Link(oldResolved, newResolved); err != nil {
+		var linkErr *os.LinkError
+		if errors.As(err, &linkErr) {
+			return &os.LinkError{Op: linkErr.Op, Old: oldname, New: newname, Err: linkErr.Err}
+		}
+		return err
+	}
+	return nil
+}
+
+func (root *rootFilesystem) Symlink(oldname, newname string) error {
+	path, err := root.resolve("symlink", newname, false)
+	if err != nil {
+		return err
+	}
+	if err := os.Symlink(oldname, path); err != nil {
+		var linkErr *os.LinkError
+		if errors.As(err, &linkErr) {
+			return &os.LinkError{Op: linkErr.Op, Old: oldname, New: newname, Err: linkErr.Err}
+		}
+		return err
+	}
+	return nil
+}
+
+func (root *rootFilesystem) Readlink(name string) (string, error) {
+	path, err := root.resolve("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	target, err := os.Readlink(path)
+	return target, root.hide(err, name)
+}
+
+func (root *rootFilesystem) Lstat(name string) (fs.FileInfo, error) {
+	path, err := root.resolve("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(path)
+	return info, root.hide(err, name)
+}
+
+// realpath resolves every symbolic link in the path, the path stays relative to the root.
+func (root *rootFilesystem) realpath(name string) (string, error) {
+	path, err := root.resolve("resolve", name, true)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", root.hide(err, name)
+	}
+	return filepath.Rel(root.base, path)
+}
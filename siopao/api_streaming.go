@@ -9,7 +9,7 @@ import "github.com/ShindouMihou/siopao/streaming"
 func (file *File) Reader() (*streaming.Reader, error) {
 	f, err := file.openRead()
 	if err != nil {
-		return nil, err
+		return nil, file.wrap("open", err)
 	}
 	return streaming.NewReader(f), nil
 }
@@ -35,7 +35,7 @@ func (file *File) TextReader() (*streaming.TextReader, error) {
 func (file *File) WriterSize(overwrite bool, size int) (*streaming.Writer, error) {
 	f, err := file.openWrite(overwrite)
 	if err != nil {
-		return nil, err
+		return nil, file.wrap("open", err)
 	}
 	return streaming.NewWriterSize(f, size), nil
 }
@@ -48,7 +48,49 @@ func (file *File) WriterSize(overwrite bool, size int) (*streaming.Writer, error
 func (file *File) Writer(overwrite bool) (*streaming.Writer, error) {
 	f, err := file.openWrite(overwrite)
 	if err != nil {
-		return nil, err
+		return nil, file.wrap("open", err)
 	}
 	return streaming.NewWriter(f), nil
 }
+
+// RotatingWriter opens a rotating write stream that appends to the file and moves it aside once it reaches a size,
+// an amount of lines or an age, see streaming.RotatingWriter for how to configure the rotation.
+//
+// This is only supported for files on the filesystem of the operating system, any other Filesystem fails with
+// ErrUnsupported. We recommend using streaming.RotatingWriter's End method to close the writer.
+func (file *File) RotatingWriter() (*streaming.RotatingWriter, error) {
+	if _, ok := file.fs.(OSFilesystem); !ok {
+		return nil, file.wrap("open", ErrUnsupported)
+	}
+	writer, err := streaming.NewRotatingWriter(file.path)
+	if err != nil {
+		return nil, file.wrap("open", err)
+	}
+	return writer, nil
+}
+
+// AsyncWriter opens a write stream whose writes are queued and written into the file by a background goroutine, see
+// streaming.AsyncWriter for how to configure the flushing and what happens when the queue of the given size is full.
+//
+// This causes the file to be opened, we recommend using streaming.AsyncWriter's End method to close the writer as it
+// writes whatever is still queued, flushes and closes the file.
+func (file *File) AsyncWriter(overwrite bool, queue int) (*streaming.AsyncWriter, error) {
+	writer, err := file.Writer(overwrite)
+	if err != nil {
+		return nil, err
+	}
+	return writer.Async(queue), nil
+}
+
+// ResumableReader opens a line stream to the file that saves its position into the checkpoint file every so often,
+// allowing the reading to resume from there after a crash, see streaming.ResumableReader for how often the
+// checkpoints are saved. The checkpoint file is always on the filesystem of the operating system.
+//
+// This causes the file to be opened, the returned streaming.ResumableReader closes it once EachLine is done.
+func (file *File) ResumableReader(checkpoint string) (*streaming.ResumableReader, error) {
+	f, err := file.openRead()
+	if err != nil {
+		return nil, file.wrap("open", err)
+	}
+	return streaming.NewResumableReader(f, checkpoint), nil
+}
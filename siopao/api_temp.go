@@ -0,0 +1,56 @@
+package siopao
+
+import (
+	"errors"
+	"os"
+)
+
+// TempFile creates a new temporary file in the default directory for temporary files, see os.CreateTemp for how
+// the pattern is used to name the file. The file is created empty and closed right away, similar to Open, it is
+// only opened when needed.
+//
+// It is up to you to remove the file once you are done with it, preferably using Cleanup.
+func TempFile(pattern string) (*File, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return nil, err
+	}
+	file := Open(f.Name())
+	file.isDir = 0
+	file.close(f)
+	return file, nil
+}
+
+// TempDir creates a new temporary directory in the default directory for temporary files, see os.MkdirTemp for how
+// the pattern is used to name the directory.
+//
+// It is up to you to remove the directory once you are done with it, preferably using Cleanup, or use Scratch instead
+// which removes the directory for you.
+func TempDir(pattern string) (*File, error) {
+	path, err := os.MkdirTemp("", pattern)
+	if err != nil {
+		return nil, err
+	}
+	file := Open(path)
+	file.isDir = 1
+	return file, nil
+}
+
+// Scratch creates a temporary directory, passes it to the function and removes the directory, along with its contents,
+// once the function returns, even when it fails or panics.
+func Scratch(fn func(dir *File) error) (err error) {
+	dir, err := TempDir("siopao-scratch-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, dir.Cleanup())
+	}()
+	return fn(dir)
+}
+
+// Cleanup removes the file, or directory along with its contents, it is a short-hand of DeleteRecursively that
+// is intended for temporary files and directories created with TempFile, TempDir or Scratch.
+func (file *File) Cleanup() error {
+	return file.DeleteRecursively()
+}
@@ -0,0 +1,270 @@
+package siopao
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trashTime is the format of the DeletionDate in the trashinfo files, which is in local time.
+const trashTime = "2006-01-02T15:04:05"
+
+// TrashCan is a trash directory that follows the FreeDesktop.org trash specification, which is what file managers on
+// Linux use, items are moved into the "files" folder while their original path and deletion date are written into
+// the "info" folder. Use Trash for the trash of the user, or TrashAt for a trash directory of your own.
+//
+// A TrashCan only works with files on the filesystem of the operating system, files on any other Filesystem fail with
+// ErrUnsupported.
+type TrashCan struct {
+	dir string
+}
+
+// TrashedFile is an item inside a TrashCan.
+type TrashedFile struct {
+	// Name is the name of the item inside the TrashCan, which is unique inside the TrashCan, this is usually the
+	// name of the original file.
+	Name string
+	// OriginalPath is the absolute path that the item was trashed from.
+	OriginalPath string
+	// DeletedAt is when the item was trashed.
+	DeletedAt time.Time
+}
+
+// Trash gets the TrashCan of the user, which is $XDG_DATA_HOME/Trash, or ~/.local/share/Trash when XDG_DATA_HOME is
+// not set, or is a relative path, which the XDG specification says to ignore. The directory is created when the first
+// item is trashed.
+func Trash() (*TrashCan, error) {
+	data := os.Getenv("XDG_DATA_HOME")
+	if !filepath.IsAbs(data) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		data = filepath.Join(home, ".local", "share")
+	}
+	return TrashAt(filepath.Join(data, "Trash")), nil
+}
+
+// TrashAt gets the TrashCan at the given directory, the directory is created when the first item is trashed.
+func TrashAt(dir string) *TrashCan {
+	return &TrashCan{dir: dir}
+}
+
+// Trash moves the file, or directory, into the TrashCan of the user instead of deleting it permanently, allowing it
+// to be restored later on. See Trash and TrashCan.Put for more details.
+func (file *File) Trash() (*TrashedFile, error) {
+	can, err := Trash()
+	if err != nil {
+		return nil, file.wrap("trash", err)
+	}
+	return can.Put(file)
+}
+
+// Path gets the path of the TrashCan.
+func (can *TrashCan) Path() string {
+	return can.dir
+}
+
+// Put moves the file, or directory, into the TrashCan. When an item with the same name is already in the TrashCan,
+// the item is named with a numbered suffix instead, such as "report (1).txt".
+//
+// Similar to Move, the file is copied into the TrashCan when it is on another device.
+func (can *TrashCan) Put(file *File) (*TrashedFile, error) {
+	item, err := can.put(file)
+	return item, file.wrap("trash", err)
+}
+
+// List lists the items inside the TrashCan, from the oldest to the newest. Items whose trashinfo cannot be read are
+// left out.
+func (can *TrashCan) List() ([]TrashedFile, error) {
+	entries, err := os.ReadDir(filepath.Join(can.dir, "info"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, can.wrap("list", err)
+	}
+	var items []TrashedFile
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".trashinfo")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		item, err := can.info(name)
+		if err != nil {
+			continue
+		}
+		items = append(items, *item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DeletedAt.Before(items[j].DeletedAt)
+	})
+	return items, nil
+}
+
+// Restore moves the item with the given name back into its original path, failing with ErrExists when something
+// already exists at the original path. The original folders are created when they no longer exist.
+func (can *TrashCan) Restore(name string) (*File, error) {
+	item, err := can.info(name)
+	if err != nil {
+		return nil, can.wrap("restore", err)
+	}
+	trashed := Open(filepath.Join(can.dir, "files", name))
+	if err := trashed.move(item.OriginalPath, &options{policy: FailIfExists}); err != nil {
+		return nil, trashed.wrap("restore", err)
+	}
+	if err := os.Remove(can.infoPath(name)); err != nil {
+		return nil, can.wrap("restore", err)
+	}
+	return Open(item.OriginalPath), nil
+}
+
+// Empty permanently deletes the items that were trashed longer than the given duration ago, a duration of zero
+// deletes every item inside the TrashCan.
+func (can *TrashCan) Empty(olderThan time.Duration) error {
+	items, err := can.List()
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(-olderThan)
+	for _, item := range items {
+		if olderThan > 0 && item.DeletedAt.After(deadline) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(can.dir, "files", item.Name)); err != nil {
+			return can.wrap("empty", err)
+		}
+		if err := os.Remove(can.infoPath(item.Name)); err != nil {
+			return can.wrap("empty", err)
+		}
+	}
+	return nil
+}
+
+func (can *TrashCan) put(file *File) (*TrashedFile, error) {
+	if _, ok := file.fs.(OSFilesystem); !ok {
+		return nil, ErrUnsupported
+	}
+	path, err := filepath.Abs(file.path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Lstat(path); err != nil {
+		return nil, err
+	}
+	for _, dir := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(can.dir, dir), 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	item := &TrashedFile{OriginalPath: path, DeletedAt: time.Now().Truncate(time.Second)}
+	info, err := can.reserve(item)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.move(filepath.Join(can.dir, "files", item.Name), &options{policy: FailIfExists}); err != nil {
+		return nil, errors.Join(err, os.Remove(info))
+	}
+	return item, nil
+}
+
+// reserve writes the trashinfo of the item under the first free name, which is created exclusively, as the
+// specification requires, so that two processes never pick the same name.
+func (can *TrashCan) reserve(item *TrashedFile) (string, error) {
+	base := filepath.Base(item.OriginalPath)
+	ext := filepath.Ext(base)
+	content := "[Trash Info]\nPath=" + (&url.URL{Path: item.OriginalPath}).EscapedPath() +
+		"\nDeletionDate=" + item.DeletedAt.Format(trashTime) + "\n"
+	for i := 0; ; i++ {
+		item.Name = base
+		if i > 0 {
+			item.Name = strings.TrimSuffix(base, ext) + " (" + strconv.Itoa(i) + ")" + ext
+		}
+		if _, err := os.Lstat(filepath.Join(can.dir, "files", item.Name)); err == nil {
+			continue
+		}
+		path := can.infoPath(item.Name)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = f.WriteString(content)
+		if err = errors.Join(err, f.Close()); err != nil {
+			return "", errors.Join(err, os.Remove(path))
+		}
+		return path, nil
+	}
+}
+
+// info reads the trashinfo of the item with the given name.
+func (can *TrashCan) info(name string) (*TrashedFile, error) {
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	f, err := os.Open(can.infoPath(name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	item := &TrashedFile{Name: name}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Path":
+			path, err := url.PathUnescape(value)
+			if err != nil {
+				return nil, err
+			}
+			item.OriginalPath = path
+		case "DeletionDate":
+			date, err := time.ParseInLocation(trashTime, value, time.Local)
+			if err != nil {
+				return nil, err
+			}
+			item.DeletedAt = date
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if item.OriginalPath == "" {
+		return nil, &fs.PathError{Op: "read", Path: can.infoPath(name), Err: fs.ErrInvalid}
+	}
+	if !filepath.IsAbs(item.OriginalPath) {
+		// relative paths are relative to the parent of the trash directory, which is how trash directories at the
+		// top of other devices store their paths.
+		item.OriginalPath = filepath.Join(filepath.Dir(can.dir), item.OriginalPath)
+	}
+	return item, nil
+}
+
+func (can *TrashCan) infoPath(name string) string {
+	return filepath.Join(can.dir, "info", name+".trashinfo")
+}
+
+func (can *TrashCan) wrap(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var opErr *OpError
+	if errors.As(err, &opErr) {
+		return err
+	}
+	return &OpError{Op: op, Path: can.dir, Err: err}
+}
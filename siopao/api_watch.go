@@ -0,0 +1,198 @@
+package siopao
+
+import (
+	"context"
+	"time"
+)
+
+type EventKind uint8
+
+const (
+	Created EventKind = iota + 1
+	Modified
+	Removed
+	Renamed
+	Chmod
+)
+
+// String gets the name of the EventKind.
+func (kind EventKind) String() string {
+	switch kind {
+	case Created:
+		return "created"
+	case Modified:
+		return "modified"
+	case Removed:
+		return "removed"
+	case Renamed:
+		return "renamed"
+	case Chmod:
+		return "chmod"
+	default:
+		return "unknown"
+	}
+}
+
+type Event struct {
+	Kind EventKind
+	File *File
+	// OldPath is the previous path of the file, this is only set on Renamed events when the previous path is known.
+	OldPath string
+}
+
+// Watch watches the file, or directory, for changes until the context is cancelled, after which the channel is
+// closed. When the file is a directory, its subdirectories are also watched, including the ones that are created
+// after the watch has started, you can limit this with WithMaxDepth where a depth of 1 only watches the direct
+// children of the directory.
+//
+// On Linux, this uses inotify and falls back to polling when inotify cannot be used, other platforms, and files that
+// are not from the OSFilesystem, always poll. Events
+// are debounced per path, which means that bursts of events are merged into one event, for example, editors
+// that save by writing a temporary file and renaming it over the original produce a single Modified event.
+//
+// Watch supports the following options: WithMaxDepth, WithDebounce and WithPolling.
+func (file *File) Watch(ctx context.Context, opts ...Option) (<-chan Event, error) {
+	options := newOptions(opts)
+	if _, err := file.UncachedIsDir(); err != nil {
+		return nil, file.wrap("watch", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	raw := make(chan Event, 64)
+
+	var err error
+	if _, native := file.fs.(OSFilesystem); options.pollInterval > 0 || !native {
+		err = file.watchPoll(ctx, options, raw)
+	} else if err = file.watchNative(ctx, options, raw); err != nil {
+		err = file.watchPoll(ctx, options, raw)
+	}
+	if err != nil {
+		cancel()
+		return nil, file.wrap("watch", err)
+	}
+
+	events := make(chan Event, 64)
+	go func() {
+		defer cancel()
+		debounce(ctx, options.debounce, raw, events)
+	}()
+	return events, nil
+}
+
+type pendingEvent struct {
+	event    Event
+	deadline time.Time
+	// ephemeral is set when the file was created and removed within the same debounce window, these events are
+	// never sent, but are kept around so that a rename of the file can be detected as an atomic save.
+	ephemeral bool
+}
+
+func debounce(ctx context.Context, delay time.Duration, in <-chan Event, out chan<- Event) {
+	defer close(out)
+
+	pending := make(map[string]*pendingEvent)
+	var order []string
+	var timer *time.Timer
+	var tick <-chan time.Time
+
+	flush := func(all bool) bool {
+		now := time.Now()
+		var next time.Time
+		remaining := order[:0]
+		for _, path := range order {
+			entry, ok := pending[path]
+			if !ok {
+				continue
+			}
+			if !all && entry.deadline.After(now) {
+				if next.IsZero() || entry.deadline.Before(next) {
+					next = entry.deadline
+				}
+				remaining = append(remaining, path)
+				continue
+			}
+			delete(pending, path)
+			if entry.ephemeral {
+				continue
+			}
+			select {
+			case out <- entry.event:
+			case <-ctx.Done():
+				return false
+			}
+		}
+		order = remaining
+		if timer != nil {
+			timer.Stop()
+			timer, tick = nil, nil
+		}
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			tick = timer.C
+		}
+		return true
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+			if !flush(false) {
+				return
+			}
+		case event, ok := <-in:
+			if !ok {
+				flush(true)
+				return
+			}
+			path := event.File.Path()
+			if _, ok := pending[path]; !ok {
+				order = append(order, path)
+			}
+			merge(pending, event, time.Now().Add(delay))
+			if !flush(delay <= 0) {
+				return
+			}
+		}
+	}
+}
+
+func merge(pending map[string]*pendingEvent, event Event, deadline time.Time) {
+	path := event.File.Path()
+	if event.Kind == Renamed && event.OldPath != "" {
+		if previous, ok := pending[event.OldPath]; ok {
+			delete(pending, event.OldPath)
+			if previous.ephemeral {
+				event = Event{Kind: Modified, File: event.File}
+			}
+		}
+	}
+
+	entry, ok := pending[path]
+	if !ok {
+		pending[path] = &pendingEvent{event: event, deadline: deadline}
+		return
+	}
+	entry.deadline = deadline
+
+	previous := entry.event.Kind
+	switch {
+	case entry.ephemeral && event.Kind == Created:
+		entry.ephemeral = false
+		entry.event = event
+	case entry.ephemeral:
+		return
+	case previous == Created && event.Kind == Removed:
+		entry.ephemeral = true
+		entry.event = event
+	case previous == Created && (event.Kind == Modified || event.Kind == Chmod):
+		return
+	case previous == Removed && event.Kind == Created:
+		entry.event = Event{Kind: Modified, File: event.File}
+	case previous == Modified && event.Kind == Chmod:
+		return
+	default:
+		entry.event = event
+	}
+}
@@ -4,22 +4,30 @@ import "github.com/ShindouMihou/siopao/paopao"
 
 // Write writes, or appends if the file exists, the content to the file.
 // Anything other than string, io.Reader and []byte is marshaled into Json with the paopao.Marshal.
-func (file *File) Write(t any) error {
-	return file.wrtany(false, t)
+//
+// Write supports the following options: WithProgress, WithProgressChannel and WithProgressInterval.
+func (file *File) Write(t any, opts ...Option) error {
+	return file.wrap("write", file.wrtany(false, t, newOptions(opts)))
 }
 
 // Overwrite overwrites the file and writes the content to the file.
 // Anything other than string, io.Reader and []byte is marshaled into Json with the paopao.Marshal.
-func (file *File) Overwrite(t any) error {
-	return file.wrtany(true, t)
+//
+// Overwrite supports the following options: WithProgress, WithProgressChannel and WithProgressInterval.
+func (file *File) Overwrite(t any, opts ...Option) error {
+	return file.wrap("write", file.wrtany(true, t, newOptions(opts)))
 }
 
 // WriteMarshal works like Write, but marshals anything other than string and []byte with the provided marshal.
-func (file *File) WriteMarshal(marshal paopao.Marshaller, t any) error {
-	return file.wrtmarshal(marshal, false, t)
+//
+// WriteMarshal supports the following options: WithProgress, WithProgressChannel and WithProgressInterval.
+func (file *File) WriteMarshal(marshal paopao.Marshaller, t any, opts ...Option) error {
+	return file.wrap("write", file.wrtmarshal(marshal, false, t, newOptions(opts)))
 }
 
 // OverwriteMarshal works like Overwrite, but marshals anything other than string and []byte with the provided marshal.
-func (file *File) OverwriteMarshal(marshal paopao.Marshaller, t any) error {
-	return file.wrtmarshal(marshal, true, t)
+//
+// OverwriteMarshal supports the following options: WithProgress, WithProgressChannel and WithProgressInterval.
+func (file *File) OverwriteMarshal(marshal paopao.Marshaller, t any, opts ...Option) error {
+	return file.wrap("write", file.wrtmarshal(marshal, true, t, newOptions(opts)))
 }
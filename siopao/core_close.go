@@ -1,8 +1,8 @@
 package siopao
 
-import "os"
+import "io"
 
-func (file *File) close(f *os.File) {
+func (file *File) close(f io.Closer) {
 	// ignore the error, it's likely that it just already called
 	_ = f.Close()
 }
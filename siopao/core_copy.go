@@ -0,0 +1,87 @@
+package siopao
+
+import (
+	"github.com/ShindouMihou/siopao/streaming"
+	"io"
+	"io/fs"
+	"os"
+)
+
+func (file *File) copy(dest string, options *options) error {
+	_, err := file.place(dest, options.policy, func(destination *File) error {
+		return file.copyTo(destination, options)
+	})
+	return err
+}
+
+func (file *File) copyTo(destination *File, options *options) error {
+	if options.strategy == HardlinkCopy && file.hardlink(destination, options.policy.exclusive()) {
+		size := file.size()
+		options.tracker(size).From(size).Finish()
+		return nil
+	}
+	fn := func(destFile WritableFile) (*any, error) {
+		srcFile, err := file.openRead()
+		if err != nil {
+			return nil, err
+		}
+		defer file.close(srcFile)
+		tracker := options.tracker(file.size())
+		if err = copyContents(options.strategy, destFile, srcFile, tracker); err != nil {
+			return nil, err
+		}
+		tracker.Finish()
+		return nil, nil
+	}
+	_, err := output(destination, options.policy, fn)
+	return err
+}
+
+// copyContents copies the source into the destination with the strategy, falling back to io.Copy when the strategy
+// isn't supported, or when either file is not an *os.File.
+func copyContents(strategy CopyStrategy, dst WritableFile, src fs.File, tracker *streaming.Tracker) error {
+	if strategy != StreamCopy {
+		dstFile, ok := dst.(*os.File)
+		srcFile, ok2 := src.(*os.File)
+		if ok && ok2 {
+			done, err := copyNative(strategy, dstFile, srcFile, tracker)
+			if done || err != nil {
+				return err
+			}
+		}
+	}
+	_, err := io.Copy(dst, track(src, tracker))
+	return err
+}
+
+// hardlink links the destination to the file, replacing the destination if it exists unless exclusive, this returns
+// false when the file cannot be linked, such as when the Filesystem isn't a LinkFilesystem or the destination is on
+// another device. The link is created under a temporary name first and then renamed over the destination, so the
+// destination is never missing.
+func (file *File) hardlink(dest *File, exclusive bool) bool {
+	links, ok := file.fs.(LinkFilesystem)
+	if !ok {
+		return false
+	}
+	if exclusive {
+		return dest.MkdirParent() == nil && links.Link(file.path, dest.path) == nil
+	}
+	if info, err := file.fs.Stat(file.path); err == nil {
+		// renaming a link over another link to the same file does nothing, leaving the temporary link behind.
+		if existing, err := dest.fs.Stat(dest.path); err == nil && os.SameFile(info, existing) {
+			return true
+		}
+	}
+	if err := dest.MkdirParent(); err != nil {
+		return false
+	}
+	temp := dest.path + ".siopao-link"
+	if err := links.Link(file.path, temp); err != nil {
+		return false
+	}
+	if err := file.fs.Rename(temp, dest.path); err != nil {
+		_ = file.fs.Remove(temp)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+package siopao
+
+import (
+	"errors"
+	"github.com/ShindouMihou/siopao/streaming"
+	"golang.org/x/sys/unix"
+	"io"
+	"os"
+)
+
+// copyChunk is the most that copy_file_range copies at once, this keeps the progress reported as it goes.
+const copyChunk = 8 << 20
+
+// copyNative copies the source into the destination with the strategy, this returns false, without an error, when
+// the strategy isn't supported for the files, in which case the rest is copied with io.Copy from the current offsets.
+func copyNative(strategy CopyStrategy, dst *os.File, src *os.File, tracker *streaming.Tracker) (bool, error) {
+	switch strategy {
+	case ReflinkCopy:
+		if err := unix.IoctlFileClone(int(dst.Fd()), int(src.Fd())); err == nil {
+			info, err := src.Stat()
+			if err != nil {
+				return true, err
+			}
+			tracker.Add(info.Size())
+			return true, nil
+		}
+		return copyRange(dst, src, tracker)
+	case KernelCopy:
+		return copyRange(dst, src, tracker)
+	case SparseCopy:
+		return copySparse(dst, src, tracker)
+	}
+	return false, nil
+}
+
+func copyRange(dst *os.File, src *os.File, tracker *streaming.Tracker) (bool, error) {
+	for {
+		n, err := unix.CopyFileRange(int(src.Fd()), nil, int(dst.Fd()), nil, copyChunk, 0)
+		if err != nil {
+			if unsupported(err) {
+				return false, nil
+			}
+			return true, err
+		}
+		if n == 0 {
+			return true, nil
+		}
+		tracker.Add(int64(n))
+	}
+}
+
+func copySparse(dst *os.File, src *os.File, tracker *streaming.Tracker) (bool, error) {
+	info, err := src.Stat()
+	if err != nil {
+		return true, err
+	}
+	size := info.Size()
+	var offset int64
+	for offset < size {
+		data, err := src.Seek(offset, unix.SEEK_DATA)
+		if errors.Is(err, unix.ENXIO) {
+			// there is no data after the offset, only a hole until the end of the file.
+			break
+		}
+		if err != nil {
+			if offset == 0 && unsupported(err) {
+				return false, nil
+			}
+			return true, err
+		}
+		hole, err := src.Seek(data, unix.SEEK_HOLE)
+		if err != nil {
+			return true, err
+		}
+		if _, err := src.Seek(data, io.SeekStart); err != nil {
+			return true, err
+		}
+		if _, err := dst.Seek(data, io.SeekStart); err != nil {
+			return true, err
+		}
+		if _, err := io.CopyN(dst, src, hole-data); err != nil {
+			return true, err
+		}
+		// holes are counted as done, as they are kept by truncating the destination to the size of the source.
+		tracker.Add(hole - offset)
+		offset = hole
+	}
+	tracker.Add(size - offset)
+	return true, dst.Truncate(size)
+}
+
+// unsupported checks whether the error means that the operation is not supported for the files, rather than it
+// failing, such as when the files are on different filesystems.
+func unsupported(err error) bool {
+	return errors.Is(err, unix.EXDEV) || errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EOPNOTSUPP) ||
+		errors.Is(err, unix.EINVAL) || errors.Is(err, unix.EBADF)
+}
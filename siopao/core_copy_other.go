@@ -0,0 +1,13 @@
+//go:build !linux
+
+package siopao
+
+import (
+	"github.com/ShindouMihou/siopao/streaming"
+	"os"
+)
+
+// copyNative is only supported on Linux, every strategy falls back to StreamCopy elsewhere.
+func copyNative(strategy CopyStrategy, dst *os.File, src *os.File, tracker *streaming.Tracker) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,125 @@
+package siopao
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// guard checks that the path is safe to delete recursively, see DeleteRecursively.
+func (file *File) guard() error {
+	if strings.TrimSpace(file.path) == "" {
+		return fmt.Errorf("%w: the path is empty", ErrUnsafeDelete)
+	}
+	if _, ok := file.fs.(OSFilesystem); !ok {
+		return nil
+	}
+
+	path, err := filepath.Abs(file.path)
+	if err != nil {
+		return err
+	}
+	// only the parent is resolved, as deleting a symbolic link deletes the link rather than what it points to.
+	if parent, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		path = filepath.Join(parent, filepath.Base(path))
+	}
+	if path == filepath.VolumeName(path)+string(filepath.Separator) {
+		return fmt.Errorf("%w: %s is the root of the filesystem", ErrUnsafeDelete, path)
+	}
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		if resolved, err := filepath.EvalSymlinks(home); err == nil {
+			home = resolved
+		}
+		if rel, err := filepath.Rel(path, home); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("%w: %s contains the home directory", ErrUnsafeDelete, path)
+		}
+	}
+	return nil
+}
+
+// deletable lists the file and everything inside it, failing with ErrUnsafeDelete once there are more files, or
+// bytes, than the limits allow.
+func (file *File) deletable(options *options) ([]*File, error) {
+	info, err := file.lstat()
+	if err != nil {
+		return nil, err
+	}
+	files := []*File{file}
+	var count, size int64
+	check := func(info fs.FileInfo) error {
+		if info.IsDir() {
+			return nil
+		}
+		count++
+		size += info.Size()
+		if options.maxFiles > 0 && count > options.maxFiles {
+			return fmt.Errorf("%w: more than %d files", ErrUnsafeDelete, options.maxFiles)
+		}
+		if options.maxBytes > 0 && size > options.maxBytes {
+			return fmt.Errorf("%w: more than %d bytes", ErrUnsafeDelete, options.maxBytes)
+		}
+		return nil
+	}
+	if err := check(info); err != nil || !info.IsDir() {
+		return files, err
+	}
+
+	err = file.walk(1, newOptions(nil), nil, func(child *File) error {
+		files = append(files, child)
+		info, err := child.lstat()
+		if err != nil {
+			return err
+		}
+		return check(info)
+	})
+	return files, err
+}
+
+// lstat gets the information of the file without following symbolic links, when the Filesystem supports them.
+func (file *File) lstat() (fs.FileInfo, error) {
+	if links, ok := file.fs.(LinkFilesystem); ok {
+		return links.Lstat(file.path)
+	}
+	return file.fs.Stat(file.path)
+}
+
+func (file *File) shred(passes int) error {
+	info, err := file.lstat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return &fs.PathError{Op: "shred", Path: file.path, Err: syscall.EISDIR}
+	}
+	if !info.Mode().IsRegular() {
+		return &fs.PathError{Op: "shred", Path: file.path, Err: fs.ErrInvalid}
+	}
+	if passes < 1 {
+		passes = 1
+	}
+
+	f, err := file.fs.OpenFile(file.path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer file.close(f)
+	for i := 0; i < passes; i++ {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(f, rand.Reader, info.Size()); err != nil {
+			return err
+		}
+		if syncer, ok := f.(interface{ Sync() error }); ok {
+			if err := syncer.Sync(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
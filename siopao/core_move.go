@@ -0,0 +1,174 @@
+package siopao
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+)
+
+func (file *File) move(dest string, options *options) error {
+	_, err := file.place(dest, options.policy, func(destination *File) error {
+		if err := file.mkparent(destination.path); err != nil {
+			return err
+		}
+		err := file.rename(destination.path, options.policy)
+		if err == nil || options.atomic || !crossDevice(err) {
+			return err
+		}
+		return file.moveAcross(destination.path, options.policy)
+	})
+	return err
+}
+
+// rename renames the file to the destination, when the policy never replaces the destination, this fails with
+// fs.ErrExist instead of replacing a destination that was created after the policy resolved the path.
+//
+// This uses renameat2 with RENAME_NOREPLACE on Linux, and a hard link that is then unlinked elsewhere, directories,
+// which cannot be hard linked, on other platforms and Filesystems without links are checked right before renaming.
+func (file *File) rename(dest string, policy OverwritePolicy) error {
+	if !policy.exclusive() {
+		return file.fs.Rename(file.path, dest)
+	}
+	if _, ok := file.fs.(OSFilesystem); ok {
+		if done, err := renameNoReplace(file.path, dest); done {
+			return err
+		}
+	}
+	if links, ok := file.fs.(LinkFilesystem); ok {
+		err := links.Link(file.path, dest)
+		if err == nil {
+			return file.fs.Remove(file.path)
+		}
+		if errors.Is(err, fs.ErrExist) {
+			return err
+		}
+	}
+	if _, err := file.fs.Stat(dest); err == nil {
+		return &fs.PathError{Op: "rename", Path: dest, Err: fs.ErrExist}
+	}
+	return file.fs.Rename(file.path, dest)
+}
+
+// moveAcross moves the file by copying it into the destination, verifying the copy and deleting the source, this is
+// used when the file cannot be renamed, such as when the destination is on another device. A file is copied into a
+// temporary file that is renamed over the destination once verified, therefore a failed copy leaves both the source
+// and an existing destination untouched, while a directory that failed to copy is removed.
+func (file *File) moveAcross(dest string, policy OverwritePolicy) error {
+	info, err := file.lstat()
+	if err != nil {
+		return err
+	}
+	destination := file.derive(dest)
+	if existing, err := destination.fs.Stat(dest); err == nil && (info.IsDir() || existing.IsDir()) {
+		return &fs.PathError{Op: "move", Path: dest, Err: ErrExists}
+	}
+
+	if err := file.copyTree(destination, info, policy); err != nil {
+		if info.IsDir() {
+			return errors.Join(err, destination.fs.RemoveAll(dest))
+		}
+		return err
+	}
+	if info.IsDir() {
+		return file.fs.RemoveAll(file.path)
+	}
+	return file.fs.Remove(file.path)
+}
+
+// copyTree copies the file, or the directory and everything inside it, into the destination while keeping the
+// permissions and timestamps, every file is verified after it was copied and symbolic links are recreated as-is.
+func (file *File) copyTree(dest *File, info fs.FileInfo, policy OverwritePolicy) error {
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		return replace(dest, policy, file.copySymlink)
+	case !info.IsDir():
+		if !info.Mode().IsRegular() {
+			return &fs.PathError{Op: "copy", Path: file.path, Err: fs.ErrInvalid}
+		}
+		return replace(dest, policy, func(temp *File) error {
+			if err := file.copyVerified(temp); err != nil {
+				return err
+			}
+			return temp.preserve(info)
+		})
+	}
+
+	if err := dest.fs.MkdirAll(dest.path, info.Mode().Perm()|0700); err != nil {
+		return err
+	}
+	entries, err := file.fs.ReadDir(file.path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		child, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if err := file.Join(entry.Name()).copyTree(dest.Join(entry.Name()), child, ReplaceExisting); err != nil {
+			return err
+		}
+	}
+	return dest.preserve(info)
+}
+
+// copySymlink creates the destination as a symbolic link to the same target as the file, which is a symbolic link.
+func (file *File) copySymlink(dest *File) error {
+	links, ok := file.fs.(LinkFilesystem)
+	if !ok {
+		return &fs.PathError{Op: "copy", Path: file.path, Err: ErrUnsupported}
+	}
+	target, err := links.Readlink(file.path)
+	if err != nil {
+		return err
+	}
+	return links.Symlink(target, dest.path)
+}
+
+// copyVerified copies the contents of the file into the destination, and then reads the destination back to make
+// sure that it matches the source, failing with ErrMismatch when it doesn't.
+func (file *File) copyVerified(dest *File) error {
+	expected, err := newHash(XxHash64Checksum)
+	if err != nil {
+		return err
+	}
+	if _, err := write(dest, true, func(f WritableFile) (*any, error) {
+		src, err := file.openRead()
+		if err != nil {
+			return nil, err
+		}
+		defer file.close(src)
+		_, err = io.Copy(f, io.TeeReader(src, expected))
+		return nil, err
+	}); err != nil {
+		return err
+	}
+
+	actual, err := newHash(XxHash64Checksum)
+	if err != nil {
+		return err
+	}
+	if _, err := read(dest, func(f fs.File) (any, error) {
+		_, err := io.Copy(actual, f)
+		return nil, err
+	}); err != nil {
+		return err
+	}
+	if !bytes.Equal(expected.Sum(nil), actual.Sum(nil)) {
+		return &fs.PathError{Op: "copy", Path: dest.path, Err: ErrMismatch}
+	}
+	return nil
+}
+
+// preserve applies the permissions and modification time onto the file, if the Filesystem supports it.
+func (file *File) preserve(info fs.FileInfo) error {
+	attributes, ok := file.fs.(AttributeFilesystem)
+	if !ok {
+		return nil
+	}
+	if err := attributes.Chmod(file.path, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return attributes.Chtimes(file.path, info.ModTime(), info.ModTime())
+}
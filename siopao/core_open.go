@@ -1,31 +1,32 @@
 package siopao
 
 import (
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
-func (file *File) openRead() (*os.File, error) {
-	f, err := os.Open(file.path)
+func (file *File) openRead() (fs.File, error) {
+	f, err := file.fs.Open(file.path)
 	if err != nil {
 		return nil, err
 	}
 	return f, nil
 }
 
-func (file *File) openWrite(trunc bool) (*os.File, error) {
+func (file *File) openWrite(trunc bool) (WritableFile, error) {
 	if err := file.MkdirParent(); err != nil {
 		return nil, err
 	}
 
-	var f *os.File
+	var f WritableFile
 	var err error
 
 	if trunc {
-		f, err = os.Create(file.path)
+		f, err = file.fs.OpenFile(file.path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
 	} else {
-		f, err = os.OpenFile(file.path, os.O_APPEND|os.O_RDWR|os.O_CREATE, 0666)
+		f, err = file.fs.OpenFile(file.path, os.O_APPEND|os.O_RDWR|os.O_CREATE, 0666)
 	}
 
 	if err != nil {
@@ -40,7 +41,15 @@ func (file *File) openWrite(trunc bool) (*os.File, error) {
 	return f, nil
 }
 
-func (file *File) clear(f *os.File) error {
+// openCreate creates the file for writing, failing with fs.ErrExist when the file already exists.
+func (file *File) openCreate() (WritableFile, error) {
+	if err := file.MkdirParent(); err != nil {
+		return nil, err
+	}
+	return file.fs.OpenFile(file.path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+}
+
+func (file *File) clear(f WritableFile) error {
 	if err := f.Truncate(0); err != nil {
 		return err
 	}
@@ -50,9 +59,9 @@ func (file *File) clear(f *os.File) error {
 	return nil
 }
 
-func mkparent(path string) error {
+func (file *File) mkparent(path string) error {
 	if strings.Contains(path, "\\") || strings.Contains(path, "/") {
-		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		if err := file.fs.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,136 @@
+package siopao
+
+import (
+	"errors"
+	"io/fs"
+	"runtime"
+	"sync"
+)
+
+// errStopped is returned by a visit that was skipped because the walk was already stopped by another visit, which
+// recorded the reason itself.
+var errStopped = errors.New("walk stopped")
+
+type parallelDir struct {
+	file  *File
+	depth int
+}
+
+type parallelWalker struct {
+	workers int
+	options *options
+	visit   func(child *File, entry fs.DirEntry) error
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []parallelDir
+	pending int
+	err     error
+}
+
+func newParallelWalker(workers int, options *options, visit func(child *File, entry fs.DirEntry) error) *parallelWalker {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	walker := &parallelWalker{workers: workers, options: options, visit: visit}
+	walker.cond = sync.NewCond(&walker.mu)
+	return walker
+}
+
+func (walker *parallelWalker) run(root *File) error {
+	walker.queue = []parallelDir{{file: root, depth: 1}}
+	walker.pending = 1
+
+	var wg sync.WaitGroup
+	wg.Add(walker.workers)
+	for i := 0; i < walker.workers; i++ {
+		go func() {
+			defer wg.Done()
+			walker.work()
+		}()
+	}
+	wg.Wait()
+
+	if walker.err != nil && !errors.Is(walker.err, fs.SkipAll) {
+		return walker.err
+	}
+	return nil
+}
+
+func (walker *parallelWalker) work() {
+	for {
+		walker.mu.Lock()
+		for len(walker.queue) == 0 && walker.pending > 0 && walker.err == nil {
+			walker.cond.Wait()
+		}
+		if walker.pending == 0 || walker.err != nil {
+			walker.mu.Unlock()
+			return
+		}
+		dir := walker.queue[len(walker.queue)-1]
+		walker.queue = walker.queue[:len(walker.queue)-1]
+		walker.mu.Unlock()
+
+		dirs := walker.read(dir)
+
+		walker.mu.Lock()
+		walker.queue = append(walker.queue, dirs...)
+		walker.pending += len(dirs) - 1
+		walker.cond.Broadcast()
+		walker.mu.Unlock()
+	}
+}
+
+// stop stops the walk with the error, only the first error is kept.
+func (walker *parallelWalker) stop(err error) {
+	walker.mu.Lock()
+	defer walker.mu.Unlock()
+	if walker.err == nil {
+		walker.err = err
+	}
+	walker.cond.Broadcast()
+}
+
+// stopped checks whether the walk was stopped, in which case no more files should be visited.
+func (walker *parallelWalker) stopped() bool {
+	walker.mu.Lock()
+	defer walker.mu.Unlock()
+	return walker.err != nil
+}
+
+func (walker *parallelWalker) read(dir parallelDir) []parallelDir {
+	entries, err := dir.file.fs.ReadDir(dir.file.path)
+	if err != nil {
+		if walker.options.errorHandler != nil {
+			err = walker.options.errorHandler(dir.file, err)
+		}
+		if err != nil && !errors.Is(err, fs.SkipDir) {
+			walker.stop(err)
+		}
+		return nil
+	}
+
+	var dirs []parallelDir
+	for _, entry := range entries {
+		if walker.stopped() {
+			return nil
+		}
+		child := dir.file.child(entry)
+		if err := walker.visit(child, entry); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				if entry.IsDir() {
+					continue
+				}
+				break
+			}
+			if !errors.Is(err, errStopped) {
+				walker.stop(err)
+			}
+			return nil
+		}
+		if entry.IsDir() && (walker.options.maxDepth <= 0 || dir.depth < walker.options.maxDepth) {
+			dirs = append(dirs, parallelDir{file: child, depth: dir.depth + 1})
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,87 @@
+package siopao
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// target resolves the path that the file is copied, or moved, to according to the policy, this is empty when the
+// operation should be skipped.
+func (file *File) target(dest string, policy OverwritePolicy) (string, error) {
+	existing, err := file.fs.Stat(dest)
+	if errors.Is(err, fs.ErrNotExist) {
+		return dest, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch policy {
+	case FailIfExists:
+		return "", &fs.PathError{Op: "stat", Path: dest, Err: ErrExists}
+	case SkipExisting:
+		return "", nil
+	case KeepBoth:
+		ext := filepath.Ext(dest)
+		base := strings.TrimSuffix(dest, ext)
+		for i := 1; ; i++ {
+			candidate := base + " (" + strconv.Itoa(i) + ")" + ext
+			if _, err := file.fs.Stat(candidate); errors.Is(err, fs.ErrNotExist) {
+				return candidate, nil
+			} else if err != nil {
+				return "", err
+			}
+		}
+	case ReplaceIfNewer:
+		info, err := file.fs.Stat(file.path)
+		if err != nil {
+			return "", err
+		}
+		if !info.ModTime().After(existing.ModTime()) {
+			return "", nil
+		}
+	}
+	return dest, nil
+}
+
+// place resolves the destination according to the policy and passes it to the function, which returns false when
+// the policy skipped the destination. When the function fails because the destination was created after it was
+// resolved, the policy is applied again.
+func (file *File) place(dest string, policy OverwritePolicy, fn func(destination *File) error) (bool, error) {
+	for {
+		target, err := file.target(dest, policy)
+		if err != nil || target == "" {
+			return false, err
+		}
+		err = fn(file.derive(target))
+		if policy.exclusive() && errors.Is(err, fs.ErrExist) {
+			if _, statErr := file.fs.Stat(target); statErr != nil {
+				return false, err
+			}
+			// the destination was created after it was resolved, which means that the policy has to be applied again.
+			if policy == FailIfExists {
+				return false, &fs.PathError{Op: "open", Path: target, Err: ErrExists}
+			}
+			continue
+		}
+		return err == nil, err
+	}
+}
+
+// output opens the destination for writing according to the policy, creating it exclusively when the policy never
+// replaces an existing file, and passes it to the function.
+func output[T any](destination *File, policy OverwritePolicy, fn func(f WritableFile) (*T, error)) (*T, error) {
+	if policy.exclusive() {
+		return create(destination, fn)
+	}
+	return write(destination, true, fn)
+}
+
+// exclusive checks whether the policy never replaces the destination, in which case the destination should be
+// created with O_EXCL so that a file created after target resolved the path is not replaced.
+func (policy OverwritePolicy) exclusive() bool {
+	return policy == FailIfExists || policy == KeepBoth
+}
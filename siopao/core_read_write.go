@@ -1,17 +1,23 @@
 package siopao
 
-import "os"
+import (
+	"errors"
+	"io/fs"
+)
 
-func read[T any](file *File, fn func(f *os.File) (*T, error)) (*T, error) {
+// read opens the file for reading and passes it to the function, the value is returned as-is instead of through
+// a pointer so that a failing function can never leave the caller with a nil pointer to dereference.
+func read[T any](file *File, fn func(f fs.File) (T, error)) (T, error) {
 	f, err := file.openRead()
 	if err != nil {
-		return nil, err
+		var zero T
+		return zero, err
 	}
 	defer file.close(f)
 	return fn(f)
 }
 
-func write[T any](file *File, trunc bool, fn func(f *os.File) (*T, error)) (*T, error) {
+func write[T any](file *File, trunc bool, fn func(f WritableFile) (*T, error)) (*T, error) {
 	f, err := file.openWrite(trunc)
 	if err != nil {
 		return nil, err
@@ -19,3 +25,39 @@ func write[T any](file *File, trunc bool, fn func(f *os.File) (*T, error)) (*T,
 	defer file.close(f)
 	return fn(f)
 }
+
+// create works like write, but creates the file and fails with fs.ErrExist when the file already exists.
+func create[T any](file *File, fn func(f WritableFile) (*T, error)) (*T, error) {
+	f, err := file.openCreate()
+	if err != nil {
+		return nil, err
+	}
+	defer file.close(f)
+	return fn(f)
+}
+
+// replace passes a temporary file next to the file to the function, and renames it over the file, according to the
+// policy, once the function succeeds, the temporary file is removed otherwise, which leaves the file untouched when
+// anything fails.
+func replace(file *File, policy OverwritePolicy, fn func(temp *File) error) error {
+	temp := file.derive(file.path + ".siopao-tmp")
+	err := fn(temp)
+	if err == nil {
+		err = temp.rename(file.path, policy)
+	}
+	if err != nil {
+		if rerr := temp.fs.Remove(temp.path); rerr != nil && !errors.Is(rerr, fs.ErrNotExist) {
+			return errors.Join(err, rerr)
+		}
+		return err
+	}
+	return nil
+}
+
+// fsync commits the contents of the file to the disk, if the file supports it.
+func fsync(f WritableFile) error {
+	if syncer, ok := f.(interface{ Sync() error }); ok {
+		return syncer.Sync()
+	}
+	return nil
+}
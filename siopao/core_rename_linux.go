@@ -0,0 +1,20 @@
+package siopao
+
+import (
+	"errors"
+	"golang.org/x/sys/unix"
+	"os"
+)
+
+// renameNoReplace renames the file with renameat2 and RENAME_NOREPLACE, which fails with fs.ErrExist when the
+// destination exists, this returns false, without an error, when the kernel, or the filesystem, doesn't support it.
+func renameNoReplace(oldpath, newpath string) (bool, error) {
+	err := unix.Renameat2(unix.AT_FDCWD, oldpath, unix.AT_FDCWD, newpath, unix.RENAME_NOREPLACE)
+	if errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EINVAL) {
+		return false, nil
+	}
+	if err != nil {
+		return true, &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+	return true, nil
+}
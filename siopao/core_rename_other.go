@@ -0,0 +1,8 @@
+//go:build !linux
+
+package siopao
+
+// renameNoReplace is only supported on Linux, elsewhere, renaming without replacing falls back to a hard link.
+func renameNoReplace(oldpath, newpath string) (bool, error) {
+	return false, nil
+}
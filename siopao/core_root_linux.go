@@ -0,0 +1,80 @@
+package siopao
+
+import (
+	"errors"
+	"golang.org/x/sys/unix"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// noOpenat2 is set once openat2, which is only available since Linux 5.6, turns out to be unsupported, after which
+// the roots fall back to resolving the paths themselves.
+var noOpenat2 atomic.Bool
+
+// hold opens the directory of the root, which is kept open for the lifetime of the root so that the files can be
+// opened relative to it with openat2.
+func hold(base string) (*os.File, error) {
+	return os.Open(base)
+}
+
+// open opens the file with openat2 relative to the directory of the root, RESOLVE_BENEATH makes the kernel refuse
+// anything that would leave the root while resolving the path, which, unlike resolve, cannot be raced by another
+// process changing the directory in between.
+//
+// RESOLVE_BENEATH also refuses absolute symbolic links, even the ones that point inside the root, so those paths are
+// resolved first and then opened with openat2 once more, which keeps them confined even when the links change.
+func (root *rootFilesystem) open(name string, flag int, perm fs.FileMode) (*os.File, error) {
+	if root.dir == nil || noOpenat2.Load() {
+		return root.openResolved(name, flag, perm)
+	}
+	if filepath.IsAbs(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrEscapesRoot}
+	}
+
+	f, err := root.openBeneath(name, name, flag, perm)
+	if errors.Is(err, unix.EXDEV) {
+		path, err := root.resolve("open", name, true)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(root.base, path)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: ErrEscapesRoot}
+		}
+		f, err = root.openBeneath(rel, name, flag, perm)
+		if errors.Is(err, unix.EXDEV) {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: ErrEscapesRoot}
+		}
+		return f, err
+	}
+	if errors.Is(err, unix.ENOSYS) {
+		noOpenat2.Store(true)
+		return root.openResolved(name, flag, perm)
+	}
+	return f, err
+}
+
+// openBeneath opens the path with openat2, the name is the path that was given to the filesystem, which is used for
+// the errors and the file, so that the location of the root is not leaked.
+func (root *rootFilesystem) openBeneath(path string, name string, flag int, perm fs.FileMode) (*os.File, error) {
+	how := unix.OpenHow{
+		Flags:   uint64(flag | unix.O_CLOEXEC),
+		Resolve: unix.RESOLVE_BENEATH | unix.RESOLVE_NO_MAGICLINKS,
+	}
+	if flag&unix.O_CREAT != 0 {
+		how.Mode = uint64(perm.Perm())
+	}
+	for {
+		fd, err := unix.Openat2(int(root.dir.Fd()), path, &how)
+		// EAGAIN means that a rename, or a mount, happened while resolving the path, which is safe to retry.
+		if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return os.NewFile(uintptr(fd), name), nil
+	}
+}
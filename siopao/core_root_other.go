@@ -0,0 +1,18 @@
+//go:build !linux
+
+package siopao
+
+import (
+	"io/fs"
+	"os"
+)
+
+// hold is only needed for openat2, which is only available on Linux.
+func hold(base string) (*os.File, error) {
+	return nil, nil
+}
+
+// open opens the file at the path that resolve translated the name into, since openat2 is only available on Linux.
+func (root *rootFilesystem) open(name string, flag int, perm fs.FileMode) (*os.File, error) {
+	return root.openResolved(name, flag, perm)
+}
@@ -0,0 +1,232 @@
+package siopao
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_ATTRIB | syscall.IN_DELETE |
+	syscall.IN_DELETE_SELF | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_MOVE_SELF
+
+type inotifyWatch struct {
+	path  string
+	depth int
+}
+
+type inotifyWatcher struct {
+	fd      int
+	file    *os.File
+	root    *File
+	options *options
+	watches map[int]inotifyWatch
+	moves   map[uint32]string
+	// only is the name of the file when a single file is watched, through the watch of its parent directory.
+	only   string
+	exists bool
+}
+
+func (file *File) watchNative(ctx context.Context, options *options, out chan<- Event) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return os.NewSyscallError("inotify_init1", err)
+	}
+	watcher := &inotifyWatcher{
+		fd:      fd,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		root:    file,
+		options: options,
+		watches: make(map[int]inotifyWatch),
+		moves:   make(map[uint32]string),
+	}
+
+	isDirectory, err := file.IsDir()
+	if err == nil {
+		if isDirectory {
+			err = watcher.addTree(file.path, 0, nil)
+		} else {
+			watcher.only, watcher.exists = filepath.Base(file.path), true
+			err = watcher.add(filepath.Dir(file.path), 0)
+		}
+	}
+	if err != nil {
+		_ = watcher.file.Close()
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = watcher.file.Close()
+	}()
+	go func() {
+		defer close(out)
+		watcher.run(ctx, out)
+	}()
+	return nil
+}
+
+func (watcher *inotifyWatcher) add(path string, depth int) error {
+	wd, err := syscall.InotifyAddWatch(watcher.fd, path, inotifyMask)
+	if err != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: path, Err: err}
+	}
+	watcher.watches[wd] = inotifyWatch{path: path, depth: depth}
+	return nil
+}
+
+// addTree watches the directory and its subdirectories within the maximum depth, when emit is given, the contents
+// of the directory are sent as Created events as they may have been created before the watch was added.
+func (watcher *inotifyWatcher) addTree(path string, depth int, emit func(event Event)) error {
+	if err := watcher.add(path, depth); err != nil {
+		return err
+	}
+	return watcher.root.derive(path).Walk(func(child *File) error {
+		if emit != nil {
+			emit(Event{Kind: Created, File: child})
+		}
+		if child.isDir == 1 {
+			rel, err := filepath.Rel(path, child.path)
+			if err != nil {
+				return err
+			}
+			childDepth := depth + strings.Count(filepath.ToSlash(rel), "/") + 1
+			if !watcher.within(childDepth) {
+				return nil
+			}
+			return watcher.add(child.path, childDepth)
+		}
+		return nil
+	}, WithMaxDepth(watcher.remaining(depth)), WithErrorHandler(func(file *File, err error) error {
+		return nil
+	}))
+}
+
+func (watcher *inotifyWatcher) within(depth int) bool {
+	return watcher.options.maxDepth <= 0 || depth < watcher.options.maxDepth
+}
+
+func (watcher *inotifyWatcher) remaining(depth int) int {
+	if watcher.options.maxDepth <= 0 {
+		return 0
+	}
+	return watcher.options.maxDepth - depth
+}
+
+func (watcher *inotifyWatcher) run(ctx context.Context, out chan<- Event) {
+	buffer := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+
+	var events []Event
+	emit := func(event Event) {
+		events = append(events, event)
+	}
+	for {
+		n, err := watcher.file.Read(buffer)
+		if err != nil {
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			start := offset + syscall.SizeofInotifyEvent
+			name := strings.TrimRight(string(buffer[start:start+int(raw.Len)]), "\x00")
+			offset = start + int(raw.Len)
+			watcher.handle(raw, name, emit)
+		}
+
+		for _, event := range events {
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+		events = events[:0]
+
+		// the pair of a rename is always read together, any cookie left is a file that moved out of the tree.
+		for cookie := range watcher.moves {
+			delete(watcher.moves, cookie)
+		}
+	}
+}
+
+func (watcher *inotifyWatcher) handle(raw *syscall.InotifyEvent, name string, emit func(event Event)) {
+	if raw.Mask&syscall.IN_IGNORED != 0 {
+		delete(watcher.watches, int(raw.Wd))
+		return
+	}
+	watch, ok := watcher.watches[int(raw.Wd)]
+	if !ok {
+		return
+	}
+	if watcher.only != "" {
+		watcher.handleFile(raw, name, emit)
+		return
+	}
+
+	path := watch.path
+	if name != "" {
+		path = filepath.Join(watch.path, name)
+	} else if watch.path != watcher.root.path {
+		// events about the watched subdirectories themselves are also reported by their parent.
+		return
+	}
+	child := watcher.root.derive(path)
+	if raw.Mask&syscall.IN_ISDIR != 0 {
+		child.isDir = 1
+	}
+
+	switch {
+	case raw.Mask&syscall.IN_CREATE != 0:
+		emit(Event{Kind: Created, File: child})
+		if child.isDir == 1 && watcher.within(watch.depth+1) {
+			_ = watcher.addTree(path, watch.depth+1, emit)
+		}
+	case raw.Mask&syscall.IN_MOVED_TO != 0:
+		event := Event{Kind: Created, File: child}
+		if old, ok := watcher.moves[raw.Cookie]; ok {
+			delete(watcher.moves, raw.Cookie)
+			event = Event{Kind: Renamed, File: child, OldPath: old}
+		}
+		emit(event)
+		if child.isDir == 1 && watcher.within(watch.depth+1) {
+			_ = watcher.addTree(path, watch.depth+1, nil)
+		}
+	case raw.Mask&syscall.IN_MOVED_FROM != 0:
+		watcher.moves[raw.Cookie] = path
+		emit(Event{Kind: Removed, File: child})
+	case raw.Mask&(syscall.IN_DELETE|syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF) != 0:
+		emit(Event{Kind: Removed, File: child})
+	case raw.Mask&syscall.IN_MODIFY != 0:
+		emit(Event{Kind: Modified, File: child})
+	case raw.Mask&syscall.IN_ATTRIB != 0:
+		emit(Event{Kind: Chmod, File: child})
+	}
+}
+
+// handleFile handles the events of the parent directory when a single file is watched, which, unlike a watch on the
+// file itself, keeps working after the file was replaced by renaming another file over it.
+func (watcher *inotifyWatcher) handleFile(raw *syscall.InotifyEvent, name string, emit func(event Event)) {
+	if name != watcher.only {
+		return
+	}
+	child := watcher.root.derive(watcher.root.path)
+	switch {
+	case raw.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+		kind := Created
+		if watcher.exists {
+			kind = Modified
+		}
+		watcher.exists = true
+		emit(Event{Kind: kind, File: child})
+	case raw.Mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+		watcher.exists = false
+		emit(Event{Kind: Removed, File: child})
+	case raw.Mask&syscall.IN_MODIFY != 0:
+		emit(Event{Kind: Modified, File: child})
+	case raw.Mask&syscall.IN_ATTRIB != 0:
+		emit(Event{Kind: Chmod, File: child})
+	}
+}
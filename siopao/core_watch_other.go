@@ -0,0 +1,12 @@
+//go:build !linux
+
+package siopao
+
+import (
+	"context"
+	"errors"
+)
+
+func (file *File) watchNative(ctx context.Context, options *options, out chan<- Event) error {
+	return errors.New("native file watching is not supported on this platform")
+}
@@ -0,0 +1,108 @@
+package siopao
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sort"
+	"time"
+)
+
+type pollState struct {
+	modTime time.Time
+	size    int64
+	mode    fs.FileMode
+}
+
+func (file *File) watchPoll(ctx context.Context, options *options, out chan<- Event) error {
+	snapshot, err := file.snapshot(options)
+	if err != nil {
+		return err
+	}
+	interval := options.pollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	go func() {
+		defer close(out)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			next, err := file.snapshot(options)
+			if err != nil {
+				if !errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				next = make(map[string]pollState)
+			}
+			for _, event := range file.diff(snapshot, next) {
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+			snapshot = next
+		}
+	}()
+	return nil
+}
+
+func (file *File) snapshot(options *options) (map[string]pollState, error) {
+	snapshot := make(map[string]pollState)
+	add := func(path string) error {
+		info, err := file.fs.Stat(path)
+		if err != nil {
+			return err
+		}
+		snapshot[path] = pollState{modTime: info.ModTime(), size: info.Size(), mode: info.Mode()}
+		return nil
+	}
+
+	isDirectory, err := file.UncachedIsDir()
+	if err != nil {
+		return nil, err
+	}
+	if !isDirectory {
+		return snapshot, add(file.path)
+	}
+	return snapshot, file.Walk(func(child *File) error {
+		if err := add(child.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}, WithMaxDepth(options.maxDepth), WithErrorHandler(func(file *File, err error) error {
+		return nil
+	}))
+}
+
+func (file *File) diff(previous map[string]pollState, next map[string]pollState) []Event {
+	var events []Event
+	for path, state := range next {
+		old, ok := previous[path]
+		switch {
+		case !ok:
+			events = append(events, Event{Kind: Created, File: file.derive(path)})
+		case !old.modTime.Equal(state.modTime) || old.size != state.size:
+			events = append(events, Event{Kind: Modified, File: file.derive(path)})
+		case old.mode != state.mode:
+			events = append(events, Event{Kind: Chmod, File: file.derive(path)})
+		}
+	}
+	for path := range previous {
+		if _, ok := next[path]; !ok {
+			events = append(events, Event{Kind: Removed, File: file.derive(path)})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].File.path < events[j].File.path
+	})
+	return events
+}
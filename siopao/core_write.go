@@ -5,14 +5,17 @@ import (
 	buffer2 "github.com/ShindouMihou/siopao/internal/buffer"
 	"github.com/ShindouMihou/siopao/paopao"
 	"io"
-	"os"
 )
 
-func (file *File) wrt(trunc bool, bytes []byte) error {
-	if _, err := write(file, trunc, func(f *os.File) (*any, error) {
-		if _, err := f.Write(bytes); err != nil {
+func (file *File) wrt(trunc bool, bytes []byte, options *options) error {
+	if _, err := write(file, trunc, func(f WritableFile) (*any, error) {
+		tracker := options.tracker(int64(len(bytes)))
+		n, err := f.Write(bytes)
+		tracker.Add(int64(n))
+		if err != nil {
 			return nil, err
 		}
+		tracker.Finish()
 		return nil, nil
 	}); err != nil {
 		return err
@@ -20,16 +23,20 @@ func (file *File) wrt(trunc bool, bytes []byte) error {
 	return nil
 }
 
-func (file *File) wrtbuffer(trunc bool, buffer io.Reader) error {
-	if _, err := write(file, trunc, func(f *os.File) (*any, error) {
+func (file *File) wrtbuffer(trunc bool, buffer io.Reader, options *options) error {
+	if _, err := write(file, trunc, func(f WritableFile) (*any, error) {
+		tracker := options.tracker(length(buffer))
 		if err := buffer2.Read(buffer, 4_096, func(bytes []byte) error {
-			if _, err := f.Write(bytes); err != nil {
+			n, err := f.Write(bytes)
+			tracker.Add(int64(n))
+			if err != nil {
 				return err
 			}
 			return nil
 		}); err != nil {
 			return nil, err
 		}
+		tracker.Finish()
 		return nil, nil
 	}); err != nil {
 		return err
@@ -37,32 +44,41 @@ func (file *File) wrtbuffer(trunc bool, buffer io.Reader) error {
 	return nil
 }
 
-func (file *File) wrtjson(trunc bool, t interface{}) error {
-	return file.wrtmarshal(paopao.Marshal, trunc, t)
+func (file *File) wrtjson(trunc bool, t interface{}, options *options) error {
+	return file.wrtmarshal(paopao.Marshal, trunc, t, options)
 }
 
-func (file *File) wrtmarshal(marshal paopao.Marshaller, trunc bool, t interface{}) error {
+func (file *File) wrtmarshal(marshal paopao.Marshaller, trunc bool, t interface{}, options *options) error {
 	bytes, err := marshal(t)
 	if err != nil {
 		return err
 	}
-	return file.wrt(trunc, bytes)
+	return file.wrt(trunc, bytes, options)
 }
 
-func (file *File) wrtany(trunc bool, t any) error {
+func (file *File) wrtany(trunc bool, t any, options *options) error {
 	switch t.(type) {
 	case string:
-		return file.wrt(trunc, []byte(t.(string)))
+		return file.wrt(trunc, []byte(t.(string)), options)
 	case []byte:
-		return file.wrt(trunc, t.([]byte))
+		return file.wrt(trunc, t.([]byte), options)
 	case *bufio.Reader:
-		return file.wrtbuffer(trunc, t.(*bufio.Reader))
+		return file.wrtbuffer(trunc, t.(*bufio.Reader), options)
 	case bufio.Reader:
 		buffer := t.(bufio.Reader)
-		return file.wrtbuffer(trunc, &buffer)
+		return file.wrtbuffer(trunc, &buffer, options)
 	case io.Reader:
-		return file.wrtbuffer(trunc, t.(io.Reader))
+		return file.wrtbuffer(trunc, t.(io.Reader), options)
 	default:
-		return file.wrtjson(true, t)
+		return file.wrtjson(true, t, options)
 	}
 }
+
+// length gets the amount of unread bytes of readers that know it, such as bytes.Reader and strings.Reader, this is
+// -1 for any other reader.
+func length(reader io.Reader) int64 {
+	if r, ok := reader.(interface{ Len() int }); ok {
+		return int64(r.Len())
+	}
+	return -1
+}
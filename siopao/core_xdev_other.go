@@ -0,0 +1,13 @@
+//go:build !windows
+
+package siopao
+
+import (
+	"errors"
+	"syscall"
+)
+
+// crossDevice checks whether the error is caused by renaming a file into another device, which is EXDEV.
+func crossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
@@ -0,0 +1,13 @@
+package siopao
+
+import (
+	"errors"
+	"syscall"
+)
+
+// crossDevice checks whether the error is caused by renaming a file into another volume, which is
+// ERROR_NOT_SAME_DEVICE on Windows.
+func crossDevice(err error) bool {
+	var errno syscall.Errno
+	return errors.As(err, &errno) && errno == 17
+}
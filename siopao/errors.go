@@ -0,0 +1,61 @@
+package siopao
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+var (
+	// ErrNotDirectory is returned when a directory operation, such as Walk or Recurse, is used on a file.
+	ErrNotDirectory = errors.New("not a directory")
+	// ErrUnsupportedChecksum is returned when the ChecksumKind is not supported.
+	ErrUnsupportedChecksum = errors.New("unsupported checksum kind")
+	// ErrNonPointer is returned when unmarshalling into a value that is not a pointer.
+	ErrNonPointer = errors.New("non-pointer kind for value")
+	// ErrExists is returned when the destination of an operation already exists, this also matches fs.ErrExist.
+	ErrExists = fmt.Errorf("file already exists: %w", fs.ErrExist)
+	// ErrReadOnly is returned when trying to modify a File whose Filesystem is read-only, such as the ones opened with
+	// OpenFS over an io/fs.FS.
+	ErrReadOnly = errors.New("read-only filesystem")
+	// ErrEscapesRoot is returned when an operation on a File from Root would leave the root directory, either through
+	// the path itself (e.g. "../../etc/passwd"), an absolute path or a symbolic link that points outside the root.
+	ErrEscapesRoot = errors.New("path escapes from root")
+	// ErrMismatch is returned when a copy does not match its source after it was written, such as when a Move that
+	// crosses devices verifies the copy before deleting the source.
+	ErrMismatch = errors.New("copy does not match the source")
+	// ErrUnsupported is returned when the Filesystem of the File doesn't support the operation, such as creating links
+	// on a Filesystem that isn't a LinkFilesystem.
+	ErrUnsupported = errors.New("operation not supported by the filesystem")
+	// ErrUnsafeDelete is returned when DeleteRecursively refuses to delete the path, such as an empty path, the root of
+	// the filesystem or the home directory, or when the path has more files or bytes than the limits allow.
+	ErrUnsafeDelete = errors.New("refusing to delete")
+)
+
+// OpError is the error returned by the methods of File, it records the operation, the path of the file and the
+// cause. Use errors.Is and errors.As to inspect the cause, such as errors.Is(err, fs.ErrNotExist).
+type OpError struct {
+	Op   string
+	Path string
+	Err  error
+}
+
+func (err *OpError) Error() string {
+	return err.Op + " " + err.Path + ": " + err.Err.Error()
+}
+
+func (err *OpError) Unwrap() error {
+	return err.Err
+}
+
+// wrap wraps the error into an OpError of the operation, errors that are already an OpError are returned as they
+// are since they come from another method of File that describes the failure better.
+func (file *File) wrap(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*OpError); ok {
+		return err
+	}
+	return &OpError{Op: op, Path: file.path, Err: err}
+}
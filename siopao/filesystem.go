@@ -0,0 +1,147 @@
+package siopao
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Filesystem is the backend that a File uses for all of its operations, this allows siopao to be used against
+// anything that looks like a filesystem, such as an in-memory filesystem for tests. Files opened with Open use the
+// OSFilesystem, while OpenFS can be used to open a File with any other Filesystem.
+//
+// ReadDir must return the entries sorted by their name, similar to os.ReadDir.
+type Filesystem interface {
+	Open(name string) (fs.File, error)
+	OpenFile(name string, flag int, perm fs.FileMode) (WritableFile, error)
+	Stat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	MkdirAll(path string, perm fs.FileMode) error
+	Remove(name string) error
+	RemoveAll(path string) error
+	Rename(oldpath, newpath string) error
+}
+
+// AttributeFilesystem is a Filesystem that can change the permissions and timestamps of its files, this is optional
+// and is used to preserve them when files have to be copied, such as when a Move crosses devices.
+type AttributeFilesystem interface {
+	Filesystem
+	Chmod(name string, mode fs.FileMode) error
+	Chtimes(name string, atime time.Time, mtime time.Time) error
+}
+
+// LinkFilesystem is a Filesystem that supports hard and symbolic links, this is optional and is needed for the link
+// methods of File, such as Symlink and Readlink, and for the HardlinkCopy strategy.
+type LinkFilesystem interface {
+	Filesystem
+	Link(oldname, newname string) error
+	Symlink(oldname, newname string) error
+	Readlink(name string) (string, error)
+	Lstat(name string) (fs.FileInfo, error)
+}
+
+// WritableFile is a file that was opened for writing by a Filesystem, *os.File implements this.
+type WritableFile interface {
+	fs.File
+	io.Writer
+	io.Seeker
+	Truncate(size int64) error
+}
+
+// OSFilesystem is the Filesystem of the operating system, this is a thin wrapper over the os package.
+type OSFilesystem struct{}
+
+func (OSFilesystem) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (OSFilesystem) OpenFile(name string, flag int, perm fs.FileMode) (WritableFile, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (OSFilesystem) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (OSFilesystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (OSFilesystem) MkdirAll(path string, perm fs.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (OSFilesystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (OSFilesystem) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+func (OSFilesystem) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (OSFilesystem) Link(oldname, newname string) error {
+	return os.Link(oldname, newname)
+}
+
+func (OSFilesystem) Symlink(oldname, newname string) error {
+	return os.Symlink(oldname, newname)
+}
+
+func (OSFilesystem) Readlink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
+func (OSFilesystem) Lstat(name string) (fs.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+func (OSFilesystem) Chmod(name string, mode fs.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+func (OSFilesystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+// readOnlyFilesystem adapts an io/fs.FS into a Filesystem, every modification fails with ErrReadOnly.
+type readOnlyFilesystem struct {
+	fsys fs.FS
+}
+
+func (filesystem readOnlyFilesystem) Open(name string) (fs.File, error) {
+	return filesystem.fsys.Open(filepath.ToSlash(name))
+}
+
+func (filesystem readOnlyFilesystem) OpenFile(name string, flag int, perm fs.FileMode) (WritableFile, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: ErrReadOnly}
+}
+
+func (filesystem readOnlyFilesystem) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(filesystem.fsys, filepath.ToSlash(name))
+}
+
+func (filesystem readOnlyFilesystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(filesystem.fsys, filepath.ToSlash(name))
+}
+
+func (filesystem readOnlyFilesystem) MkdirAll(path string, perm fs.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: path, Err: ErrReadOnly}
+}
+
+func (filesystem readOnlyFilesystem) Remove(name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: ErrReadOnly}
+}
+
+func (filesystem readOnlyFilesystem) RemoveAll(path string) error {
+	return &fs.PathError{Op: "remove", Path: path, Err: ErrReadOnly}
+}
+
+func (filesystem readOnlyFilesystem) Rename(oldpath, newpath string) error {
+	return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: ErrReadOnly}
+}
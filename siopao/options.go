@@ -0,0 +1,175 @@
+package siopao
+
+import (
+	"github.com/ShindouMihou/siopao/streaming"
+	"io"
+	"time"
+)
+
+// Option configures the behavior of an operation. Not every operation honors every Option, the documentation
+// of each method mentions which of the options it supports, the rest are simply ignored.
+type Option func(options *options)
+
+type options struct {
+	maxDepth     int
+	errorHandler WalkErrorHandler
+	debounce     time.Duration
+	pollInterval time.Duration
+	encoding     ChecksumEncoding
+	progress     streaming.ProgressFunc
+	throttle     time.Duration
+	checkpoint   int64
+	atomic       bool
+	strategy     CopyStrategy
+	policy       OverwritePolicy
+	symlinks     SymlinkPolicy
+	maxFiles     int64
+	maxBytes     int64
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{debounce: 100 * time.Millisecond, throttle: 100 * time.Millisecond, checkpoint: 64 << 20}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithMaxDepth limits how deep Walk will descend into the directory, a depth of 1 only visits the direct children
+// of the directory, 2 also visits the children of those children, and so on. Zero, or any negative value, means
+// that there is no limit.
+func WithMaxDepth(depth int) Option {
+	return func(options *options) {
+		options.maxDepth = depth
+	}
+}
+
+// WithErrorHandler sets the handler that is called when Walk fails to read a directory, such as when the
+// permission is denied. The handler can return nil (or fs.SkipDir) to skip the directory and continue walking,
+// fs.SkipAll to stop walking, or any other error to abort the walk with that error.
+//
+// Without a handler, any error aborts the walk.
+func WithErrorHandler(handler WalkErrorHandler) Option {
+	return func(options *options) {
+		options.errorHandler = handler
+	}
+}
+
+// WithDebounce sets how long Watch waits for a path to stop changing before sending the merged event, this defaults
+// to 100 milliseconds. Zero, or any negative value, disables the debouncing.
+func WithDebounce(delay time.Duration) Option {
+	return func(options *options) {
+		options.debounce = delay
+	}
+}
+
+// WithPolling forces Watch to poll for changes every given interval instead of using the native mechanism of the
+// platform, this is useful for filesystems that do not support native notifications, such as network storage.
+func WithPolling(interval time.Duration) Option {
+	return func(options *options) {
+		options.pollInterval = interval
+	}
+}
+
+// WithEncoding sets the encoding of the checksum, this defaults to HexEncoding.
+func WithEncoding(encoding ChecksumEncoding) Option {
+	return func(options *options) {
+		options.encoding = encoding
+	}
+}
+
+// Progress is a snapshot of how far an operation is, see streaming.Progress.
+type Progress = streaming.Progress
+
+// WithProgress sets the function that is called with the progress of the operation as it goes, this is called at
+// most once every 100 milliseconds, which can be changed with WithProgressInterval, and once more when the operation
+// completes.
+func WithProgress(fn func(progress Progress)) Option {
+	return func(options *options) {
+		options.progress = fn
+	}
+}
+
+// WithProgressChannel works like WithProgress, but sends the progress into the channel instead, any progress that
+// doesn't fit in the channel is dropped rather than blocking the operation. The channel is never closed.
+func WithProgressChannel(ch chan<- Progress) Option {
+	return WithProgress(streaming.ProgressChannel(ch))
+}
+
+// WithProgressInterval sets how often the progress is reported at most, an interval of zero reports every write.
+func WithProgressInterval(interval time.Duration) Option {
+	return func(options *options) {
+		options.throttle = interval
+	}
+}
+
+// WithCheckpoint sets how many bytes ResumableChecksum hashes before saving its state, this defaults to 64 MiB.
+func WithCheckpoint(bytes int64) Option {
+	return func(options *options) {
+		if bytes > 0 {
+			options.checkpoint = bytes
+		}
+	}
+}
+
+// RequireAtomic makes Move and MoveTo fail when the file cannot be renamed atomically, such as when the destination
+// is on another device, instead of falling back to copying the file and deleting the source.
+func RequireAtomic() Option {
+	return func(options *options) {
+		options.atomic = true
+	}
+}
+
+// WithStrategy sets how Copy copies the contents of the file, this defaults to StreamCopy.
+func WithStrategy(strategy CopyStrategy) Option {
+	return func(options *options) {
+		options.strategy = strategy
+	}
+}
+
+// WithPolicy sets what Copy, Move, MoveTo and Rename do when the destination already exists, this defaults to
+// ReplaceExisting.
+func WithPolicy(policy OverwritePolicy) Option {
+	return func(options *options) {
+		options.policy = policy
+	}
+}
+
+// WithSymlinks sets how Walk and Recurse handle symbolic links, this defaults to ReportSymlinks.
+func WithSymlinks(policy SymlinkPolicy) Option {
+	return func(options *options) {
+		options.symlinks = policy
+	}
+}
+
+// WithMaxFiles makes DeleteRecursively fail with ErrUnsafeDelete, without deleting anything, when there are more files
+// than the given amount, directories are not counted.
+func WithMaxFiles(files int64) Option {
+	return func(options *options) {
+		options.maxFiles = files
+	}
+}
+
+// WithMaxBytes makes DeleteRecursively fail with ErrUnsafeDelete, without deleting anything, when the files add up to
+// more than the given amount of bytes.
+func WithMaxBytes(bytes int64) Option {
+	return func(options *options) {
+		options.maxBytes = bytes
+	}
+}
+
+// tracker creates a streaming.Tracker for an operation of total bytes, this is nil when there is no progress to report.
+func (options *options) tracker(total int64) *streaming.Tracker {
+	if options.progress == nil {
+		return nil
+	}
+	return streaming.NewTracker(total, options.throttle, options.progress)
+}
+
+// track counts the bytes read from the reader into the tracker, if there is any.
+func track(reader io.Reader, tracker *streaming.Tracker) io.Reader {
+	if tracker == nil {
+		return reader
+	}
+	return io.TeeReader(reader, tracker)
+}
@@ -0,0 +1,86 @@
+package siopao
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestFile_SparseCopyKeepsHoles(t *testing.T) {
+	if err := os.MkdirAll(".tests", os.ModePerm); err != nil {
+		t.Fatal("failed to create test directory: ", err)
+	}
+	sparse, err := os.Create(".tests/holes.img")
+	if err != nil {
+		t.Fatal("failed to create sparse test file: ", err)
+	}
+	if _, err := sparse.WriteAt([]byte("hello world"), 64<<20); err != nil {
+		t.Fatal("failed to write to sparse test file: ", err)
+	}
+	_ = sparse.Close()
+
+	// the blocks of a file are always counted in units of 512 bytes.
+	allocated := func(path string) int64 {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal("failed to stat ", path, ": ", err)
+		}
+		return info.Sys().(*syscall.Stat_t).Blocks * 512
+	}
+	if allocated(".tests/holes.img") >= 1<<20 {
+		t.Skip("the filesystem does not support holes")
+	}
+
+	if err := Open(".tests/holes.img").Copy(".tests/holes-copy.img", WithStrategy(SparseCopy)); err != nil {
+		t.Fatal("failed to copy sparse test file: ", err)
+	}
+	if size := allocated(".tests/holes-copy.img"); size >= 1<<20 {
+		t.Fatal("expected the holes to be kept, but the copy has ", size, " bytes allocated")
+	}
+	if text, err := Open(".tests/holes-copy.img").Text(); err != nil || len(text) != 64<<20+11 || text[64<<20:] != "hello world" {
+		t.Fatal("sparse copy does not match the source: ", err)
+	}
+}
+
+func TestRoot_Openat2(t *testing.T) {
+	if err := os.RemoveAll(".tests/openat2"); err != nil {
+		t.Fatal("failed to clean test directory: ", err)
+	}
+	if err := Open(".tests/openat2/root/inside/hello.txt").Overwrite("hello world"); err != nil {
+		t.Fatal("failed to create root test directory: ", err)
+	}
+	if err := Open(".tests/openat2/outside.txt").Overwrite("hello world"); err != nil {
+		t.Fatal("failed to create root test directory: ", err)
+	}
+	absolute, err := filepath.Abs(".tests/openat2/root/inside/hello.txt")
+	if err != nil {
+		t.Fatal("failed to get absolute path: ", err)
+	}
+	if err := os.Symlink(absolute, ".tests/openat2/root/absolute"); err != nil {
+		t.Fatal("failed to create symlink: ", err)
+	}
+	if err := os.Symlink("../outside.txt", ".tests/openat2/root/escape"); err != nil {
+		t.Fatal("failed to create symlink: ", err)
+	}
+
+	root, err := Root(".tests/openat2/root")
+	if err != nil {
+		t.Fatal("failed to open root: ", err)
+	}
+	if text, err := root.Join("absolute").Text(); err != nil || text != "hello world" {
+		t.Fatal("failed to read through absolute symlink inside root: ", text, " and ", err)
+	}
+	for _, path := range []string{"escape", "inside/../../outside.txt"} {
+		if _, err := root.Join(path).Text(); !errors.Is(err, ErrEscapesRoot) {
+			t.Fatal("reading ", path, " did not fail with ErrEscapesRoot: ", err)
+		}
+	}
+	if err := root.Join("inside/written.txt").Overwrite("written"); err != nil {
+		t.Fatal("failed to write inside root: ", err)
+	}
+	if noOpenat2.Load() {
+		t.Skip("openat2 is not supported by the kernel")
+	}
+}
@@ -2,13 +2,25 @@ package siopao
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"github.com/ShindouMihou/siopao/paopao"
 	"github.com/ShindouMihou/siopao/streaming"
+	"hash"
+	"hash/adler32"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 func TestFile_Overwrite(t *testing.T) {
@@ -94,6 +106,307 @@ func TestFile_Checksum(t *testing.T) {
 	}
 }
 
+func TestFile_ChecksumKinds(t *testing.T) {
+	file := Open(".tests/write-01.txt")
+	if err := file.Overwrite("hello world"); err != nil {
+		t.Fatal("failed to write to test text file: ", err)
+	}
+
+	expected := map[ChecksumKind]string{
+		Sha1Checksum:       "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed",
+		Sha224Checksum:     "2f05477fc24bb4faefd86517156dafdecec45b8ad3cf2522a563582b",
+		Sha384Checksum:     "fdbd8e75a67f29f701a4e040385e2e23986303ea10239211af907fcbb83578b3e417cb71ce646efd0819dd8c088de1bd",
+		Sha3_256Checksum:   "644bcc7e564373040999aac89e7622f3ca71fba1d972fd94a31c3bfbf24e3938",
+		Blake2b256Checksum: "256c83b297114d201b30179f3f0ef0cace9783622da5974326b436178aeef610",
+		Blake3Checksum:     "d74981efa70a0c880b8d8c1985d075dbcbf679b99a5f9914e5aaf96b831a9e24",
+		Crc32cChecksum:     "c99465aa",
+		XxHash64Checksum:   "45ab6734b21e6968",
+	}
+	for kind, sum := range expected {
+		checksum, err := file.Checksum(kind)
+		if err != nil {
+			t.Fatal("failed to checksum test text file with ", kind, ": ", err)
+		}
+		if checksum != sum {
+			t.Fatal(kind, " checksum is ", checksum, " instead of ", sum)
+		}
+	}
+
+	sri, err := file.Checksum(Sha256Checksum, WithEncoding(SRIEncoding))
+	if err != nil {
+		t.Fatal("failed to checksum test text file: ", err)
+	}
+	if sri != "sha256-uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek=" {
+		t.Fatal("sri checksum is ", sri)
+	}
+
+	RegisterChecksum("adler32", func() hash.Hash {
+		return adler32.New()
+	})
+	checksum, err := file.Checksum("adler32")
+	if err != nil {
+		t.Fatal("failed to checksum test text file with a registered checksum: ", err)
+	}
+	if checksum != "1a0b045d" {
+		t.Fatal("adler32 checksum is ", checksum)
+	}
+}
+
+func TestFile_Checksums(t *testing.T) {
+	file := Open(".tests/write-01.txt")
+	kinds := []ChecksumKind{Sha256Checksum, Md5Checksum, Crc32cChecksum}
+	sums, err := file.Checksums(kinds...)
+	if err != nil {
+		t.Fatal("failed to checksum test text file: ", err)
+	}
+	copied, err := file.CopyWithHashes(".tests/copy-04.txt", kinds)
+	if err != nil {
+		t.Fatal("failed to copy test text file: ", err)
+	}
+	for _, kind := range kinds {
+		checksum, err := file.Checksum(kind)
+		if err != nil {
+			t.Fatal("failed to checksum test text file: ", err)
+		}
+		if sums[kind] != checksum || copied[kind] != checksum {
+			t.Fatal(kind, " checksums do not match, got ", sums[kind], " and ", copied[kind], " instead of ", checksum)
+		}
+	}
+}
+
+func TestFile_Verify(t *testing.T) {
+	file := Open(".tests/write-01.txt")
+	for _, expected := range []string{
+		"B94D27B9934D3E08A52E52D7DA7DABFAC484EFE37A5380EE9088F7ACE2EFCDE9",
+		"uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek=",
+		"sha256-uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek=",
+	} {
+		ok, err := file.Verify(Sha256Checksum, expected)
+		if err != nil {
+			t.Fatal("failed to verify test text file: ", err)
+		}
+		if !ok {
+			t.Fatal("test text file did not match ", expected)
+		}
+	}
+	ok, err := file.Verify(Sha256Checksum, "5eb63bbbe01eeed093cb22bb8f5acdc3")
+	if err != nil {
+		t.Fatal("failed to verify test text file: ", err)
+	}
+	if ok {
+		t.Fatal("test text file matched the wrong checksum")
+	}
+}
+
+func TestFile_Manifest(t *testing.T) {
+	dir := Open(".tests/manifest")
+	if err := dir.DeleteRecursively(); err != nil {
+		t.Fatal("failed to clean up manifest test directory: ", err)
+	}
+	for _, path := range []string{"a.txt", "b.txt", "nested/c.txt"} {
+		if err := Open(".tests/manifest/" + path).Overwrite("hello world"); err != nil {
+			t.Fatal("failed to create manifest test directory: ", err)
+		}
+	}
+	if err := dir.WriteManifest(Sha256Checksum, ".tests/manifest/SHA256SUMS"); err != nil {
+		t.Fatal("failed to write manifest: ", err)
+	}
+	text, err := Open(".tests/manifest/SHA256SUMS").Text()
+	if err != nil {
+		t.Fatal("failed to read manifest: ", err)
+	}
+	sum := "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	if text != sum+"  a.txt\n"+sum+"  b.txt\n"+sum+"  nested/c.txt\n" {
+		t.Fatal("manifest does not match expected result, got: \n", text)
+	}
+
+	if err := Open(".tests/manifest/a.txt").Write("!"); err != nil {
+		t.Fatal("failed to modify manifest test directory: ", err)
+	}
+	if err := Open(".tests/manifest/b.txt").Delete(); err != nil {
+		t.Fatal("failed to modify manifest test directory: ", err)
+	}
+	if err := Open(".tests/manifest/d.txt").Overwrite("hello world"); err != nil {
+		t.Fatal("failed to modify manifest test directory: ", err)
+	}
+
+	results, err := dir.VerifyManifest(".tests/manifest/SHA256SUMS")
+	if err != nil {
+		t.Fatal("failed to verify manifest: ", err)
+	}
+	var statuses []string
+	for _, result := range results {
+		statuses = append(statuses, result.Path+":"+result.Status.String())
+	}
+	if strings.Join(statuses, ",") != "a.txt:mismatch,b.txt:missing,d.txt:extra,nested/c.txt:ok" {
+		t.Fatal("manifest verification resulted in ", statuses)
+	}
+
+	for _, kind := range []ChecksumKind{Blake3Checksum, XxHash64Checksum} {
+		manifest := ".tests/manifest/" + string(kind) + ".sums"
+		if err := dir.WriteManifest(kind, manifest); err != nil {
+			t.Fatal("failed to write ", kind, " manifest: ", err)
+		}
+		results, err := dir.VerifyManifestWith(kind, manifest)
+		if err != nil {
+			t.Fatal("failed to verify ", kind, " manifest: ", err)
+		}
+		for _, result := range results {
+			// the other manifests written in this loop are extra files.
+			if result.Status != ManifestOK && !strings.HasSuffix(result.Path, ".sums") && result.Path != "SHA256SUMS" {
+				t.Fatal("expected ", result.Path, " to be ok in the ", kind, " manifest, got ", result.Status)
+			}
+		}
+	}
+
+	// a symbolic link to a directory is skipped like the directory, and an absolute manifest path is still excluded.
+	if err := os.Symlink("nested", ".tests/manifest/linked"); err != nil {
+		t.Fatal("failed to create symlink: ", err)
+	}
+	absolute, err := filepath.Abs(".tests/manifest/ABSOLUTE")
+	if err != nil {
+		t.Fatal("failed to get absolute path: ", err)
+	}
+	if err := dir.WriteManifest(Sha256Checksum, absolute); err != nil {
+		t.Fatal("failed to write manifest with a symlink to a directory: ", err)
+	}
+	if text, err := Open(absolute).Text(); err != nil || strings.Contains(text, "ABSOLUTE") || strings.Contains(text, "linked") {
+		t.Fatal("expected the manifest to exclude itself and the symlink, got: \n", text, err)
+	}
+	results, err = dir.VerifyManifest(absolute)
+	if err != nil {
+		t.Fatal("failed to verify manifest: ", err)
+	}
+	for _, result := range results {
+		if result.Path == "ABSOLUTE" || result.Path == "linked" || result.Status != ManifestOK {
+			t.Fatal("unexpected manifest result: ", result)
+		}
+	}
+
+	for _, name := range []string{"../../etc/shadow", "/etc/shadow", "nested/../../a.txt"} {
+		if err := Open(".tests/manifest/UNSAFE").Overwrite(sum + "  " + name + "\n"); err != nil {
+			t.Fatal("failed to write manifest: ", err)
+		}
+		if _, err := dir.VerifyManifest(".tests/manifest/UNSAFE"); !errors.Is(err, fs.ErrInvalid) {
+			t.Fatal("expected manifest path ", name, " to be rejected, got ", err)
+		}
+	}
+}
+
+func TestFile_ResumableChecksum(t *testing.T) {
+	file := Open(".tests/resumable.bin")
+	if err := file.Overwrite(strings.Repeat("siopao resumable checksum ", 8192)); err != nil {
+		t.Fatal("failed to write to test file: ", err)
+	}
+	expected, err := file.Checksum(Sha256Checksum)
+	if err != nil {
+		t.Fatal("failed to checksum test file: ", err)
+	}
+
+	interrupted := errors.New("interrupted")
+	faulty := OpenFS(faultyFilesystem{failAfter: 100_000, err: interrupted}, ".tests/resumable.bin")
+	if _, err := faulty.ResumableChecksum(Sha256Checksum, ".tests/resumable.state", WithCheckpoint(16_384)); !errors.Is(err, interrupted) {
+		t.Fatal("expected checksum to be interrupted, got: ", err)
+	}
+
+	var first int64 = -1
+	sum, err := file.ResumableChecksum(Sha256Checksum, ".tests/resumable.state", WithCheckpoint(16_384), WithProgress(func(progress Progress) {
+		if first < 0 {
+			first = progress.Done
+		}
+	}))
+	if err != nil {
+		t.Fatal("failed to resume checksum: ", err)
+	}
+	if sum != expected {
+		t.Fatal("resumed checksum does not match, expected ", expected, " but got ", sum)
+	}
+	if first < 98_304 {
+		t.Fatal("checksum did not resume from the saved state, started at ", first)
+	}
+	if _, err := os.Stat(".tests/resumable.state"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("expected state file to be removed after completion: ", err)
+	}
+
+	// a state that was cut short by a crash makes the checksum start over instead of failing.
+	if err := Open(".tests/resumable.state").Overwrite(`{"kind":"sha256","off`); err != nil {
+		t.Fatal("failed to write half-written state: ", err)
+	}
+	if sum, err := file.ResumableChecksum(Sha256Checksum, ".tests/resumable.state"); err != nil || sum != expected {
+		t.Fatal("expected checksum to start over from a half-written state, got ", sum, " ", err)
+	}
+
+	if _, err := file.ResumableChecksum(Sha3_256Checksum, ".tests/resumable.state"); !errors.Is(err, ErrUnsupportedChecksum) {
+		t.Fatal("expected sha3 to not be resumable, got: ", err)
+	}
+}
+
+func TestFile_Progress(t *testing.T) {
+	content := strings.Repeat("siopao progress ", 16384)
+	size := int64(len(content))
+
+	var last Progress
+	record := WithProgress(func(progress Progress) {
+		if progress.Done < last.Done {
+			t.Fatal("progress went backwards from ", last.Done, " to ", progress.Done)
+		}
+		last = progress
+	})
+
+	file := Open(".tests/progress.txt")
+	if err := file.Overwrite(strings.NewReader(content), record, WithProgressInterval(0)); err != nil {
+		t.Fatal("failed to write to test file: ", err)
+	}
+	if last.Done != size || last.Total != size {
+		t.Fatal("unexpected progress after writing: ", last)
+	}
+
+	last = Progress{}
+	if err := file.Copy(".tests/progress-copy.txt", record); err != nil {
+		t.Fatal("failed to copy test file: ", err)
+	}
+	if last.Done != size || last.Total != size || last.ETA != 0 {
+		t.Fatal("unexpected progress after copying: ", last)
+	}
+
+	last = Progress{}
+	if _, err := file.Checksum(Sha256Checksum, record); err != nil {
+		t.Fatal("failed to checksum test file: ", err)
+	}
+	if last.Done != size {
+		t.Fatal("unexpected progress after checksum: ", last)
+	}
+
+	ch := make(chan Progress, 1024)
+	if _, err := file.CopyWithHash(Sha256Checksum, ".tests/progress-copy.txt", WithProgressChannel(ch), WithProgressInterval(0)); err != nil {
+		t.Fatal("failed to copy test file with hash: ", err)
+	}
+	if len(ch) == 0 {
+		t.Fatal("expected progress to be sent into the channel")
+	}
+
+	writer, err := Open(".tests/progress-stream.txt").Writer(true)
+	if err != nil {
+		t.Fatal("failed to open writer: ", err)
+	}
+	last = Progress{}
+	writer.OnProgress(func(progress streaming.Progress) { last = progress }, time.Hour)
+	for i := 0; i < 10; i++ {
+		if err := writer.Write("hello world\n"); err != nil {
+			t.Fatal("failed to write to stream: ", err)
+		}
+	}
+	if last.Done != 12 {
+		t.Fatal("expected only the first write to be reported before the interval, got ", last)
+	}
+	if err := writer.End(); err != nil {
+		t.Fatal("failed to end stream: ", err)
+	}
+	if last.Done != 120 || last.Total != -1 {
+		t.Fatal("unexpected progress after ending stream: ", last)
+	}
+}
+
 func TestFile_Copy(t *testing.T) {
 	file := Open(".tests/write-01.txt")
 	err := file.Copy(".tests/copy-01.txt")
@@ -171,44 +484,1487 @@ func TestFile_Recurse(t *testing.T) {
 	}
 }
 
-func TestConcurrency(t *testing.T) {
-	file := Open(".tests/concurrency-01.json")
-	wg := sync.WaitGroup{}
-	wg.Add(2)
+func TestFile_Walk(t *testing.T) {
+	for _, path := range []string{"a/1.txt", "a/deep/2.txt", "b/3.txt", "node_modules/pkg/4.txt", "z.txt"} {
+		if err := Open(".tests/walk/" + path).Overwrite("hello world"); err != nil {
+			t.Fatal("failed to create walk test tree: ", err)
+		}
+	}
+	dir := Open(".tests/walk")
 
-	written := make(chan int, 1)
-	go func() {
-		defer wg.Done()
-		i := 0
-		for i < 1000 {
-			i++
-			if err := file.Overwrite("hello " + strconv.Itoa(i)); err != nil {
-				t.Error(err)
-			}
+	var visited []string
+	if err := dir.Walk(func(file *File) error {
+		if filepath.Base(file.Path()) == "node_modules" {
+			return fs.SkipDir
+		}
+		visited = append(visited, filepath.ToSlash(file.Path()))
+		return nil
+	}); err != nil {
+		t.Fatal("failed to walk test tree: ", err)
+	}
+	expected := []string{
+		".tests/walk/a", ".tests/walk/a/1.txt", ".tests/walk/a/deep", ".tests/walk/a/deep/2.txt",
+		".tests/walk/b", ".tests/walk/b/3.txt", ".tests/walk/z.txt",
+	}
+	if strings.Join(visited, ",") != strings.Join(expected, ",") {
+		t.Fatal("walk visited ", visited, " instead of ", expected)
+	}
 
-			written <- i
+	visited = nil
+	if err := dir.Walk(func(file *File) error {
+		visited = append(visited, filepath.ToSlash(file.Path()))
+		return nil
+	}, WithMaxDepth(1)); err != nil {
+		t.Fatal("failed to walk test tree: ", err)
+	}
+	if len(visited) != 4 {
+		t.Fatal("walk with max depth 1 visited ", visited)
+	}
+
+	visited = nil
+	if err := dir.Walk(func(file *File) error {
+		visited = append(visited, filepath.ToSlash(file.Path()))
+		if strings.HasSuffix(file.Path(), "1.txt") {
+			return fs.SkipAll
 		}
-	}()
-	go func() {
-		defer wg.Done()
-		for {
-			i := <-written
-			if i == 1000 {
-				fmt.Println("written ", i)
-				text, err := file.Text()
-				if err != nil {
-					t.Error(err)
-					return
-				}
-				fmt.Println("concurrency: ", text)
+		return nil
+	}); err != nil {
+		t.Fatal("failed to walk test tree: ", err)
+	}
+	if len(visited) != 2 {
+		t.Fatal("walk did not stop after fs.SkipAll, visited ", visited)
+	}
+
+	// removing the directory before it is read makes os.ReadDir fail for it.
+	var failed []string
+	if err := dir.Walk(func(file *File) error {
+		if filepath.Base(file.Path()) == "b" {
+			return file.DeleteRecursively()
+		}
+		return nil
+	}, WithErrorHandler(func(file *File, err error) error {
+		failed = append(failed, filepath.ToSlash(file.Path()))
+		return nil
+	})); err != nil {
+		t.Fatal("error handler did not continue the walk: ", err)
+	}
+	if len(failed) != 1 || failed[0] != ".tests/walk/b" {
+		t.Fatal("error handler was called for ", failed)
+	}
+
+	if err := dir.Walk(func(file *File) error { return nil }); err != nil {
+		t.Fatal("failed to walk test tree: ", err)
+	}
+	if err := Open(".tests/walk/z.txt").Walk(func(file *File) error { return nil }); err == nil {
+		t.Fatal("walking a file did not error")
+	}
+	if err := dir.DeleteRecursively(); err != nil {
+		t.Fatal("failed to clean up walk test tree: ", err)
+	}
+}
+
+func TestFile_ParallelRecurse(t *testing.T) {
+	if err := os.RemoveAll(".tests/parallel"); err != nil {
+		t.Fatal("failed to clean test directory: ", err)
+	}
+	for i := 0; i < 50; i++ {
+		path := ".tests/parallel/" + strconv.Itoa(i%5) + "/" + strconv.Itoa(i%3) + "/" + strconv.Itoa(i) + ".txt"
+		if err := Open(path).Overwrite("hello world"); err != nil {
+			t.Fatal("failed to create parallel test tree: ", err)
+		}
+	}
+	dir := Open(".tests/parallel")
+
+	var files, dirs int
+	if err := dir.ParallelRecurse(4, func(file *File) error {
+		isDir, err := file.IsDir()
+		if err != nil {
+			return err
+		}
+		if isDir {
+			dirs++
+		} else {
+			files++
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("failed to recurse parallel test tree: ", err)
+	}
+	if files != 50 || dirs != 20 {
+		t.Fatal("parallel recurse found ", files, " files and ", dirs, " directories instead of 50 and 20")
+	}
+
+	count, err := dir.CountFiles()
+	if err != nil {
+		t.Fatal("failed to count files of parallel test tree: ", err)
+	}
+	if count != 50 {
+		t.Fatal("counted ", count, " files instead of 50")
+	}
+
+	size, err := dir.DirSize()
+	if err != nil {
+		t.Fatal("failed to get size of parallel test tree: ", err)
+	}
+	if size != int64(50*len("hello world")) {
+		t.Fatal("got size ", size, " instead of ", 50*len("hello world"))
+	}
+
+	if err := dir.DeleteRecursively(); err != nil {
+		t.Fatal("failed to clean up parallel test tree: ", err)
+	}
+
+	// nothing is visited after the function asks to stop the walk, even when other workers are in the middle of
+	// reading their directories.
+	for i := 0; i < 8*200; i++ {
+		path := ".tests/parallel/" + strconv.Itoa(i%8) + "/" + strconv.Itoa(i) + ".txt"
+		if err := Open(path).Overwrite("hello world"); err != nil {
+			t.Fatal("failed to create parallel test tree: ", err)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		visits, after := 0, 0
+		stopped := false
+		if err := dir.ParallelRecurse(4, func(file *File) error {
+			visits++
+			if stopped {
+				after++
 			}
-			if i == 1000 {
-				close(written)
-				break
+			if visits == 50 {
+				stopped = true
+				return fs.SkipAll
 			}
+			// gives the other workers the time to start reading their directories.
+			time.Sleep(100 * time.Microsecond)
+			return nil
+		}); err != nil {
+			t.Fatal("expected skip all to stop the walk without an error, got ", err)
 		}
-	}()
-	wg.Wait()
+		if after != 0 {
+			t.Fatal("expected no visits after skip all, got ", after)
+		}
+	}
+
+	if err := dir.DeleteRecursively(); err != nil {
+		t.Fatal("failed to clean up parallel test tree: ", err)
+	}
+}
+
+func expectEvent(t *testing.T, events <-chan Event, kind EventKind, path string) {
+	t.Helper()
+	for {
+		select {
+		case event := <-events:
+			if event.Kind == kind && filepath.ToSlash(event.File.Path()) == path {
+				return
+			}
+			t.Log("skipped event ", event.Kind, " ", event.File.Path())
+		case <-time.After(5 * time.Second):
+			t.Fatal("did not receive ", kind, " event for ", path)
+		}
+	}
+}
+
+// drainEvents collects the events that arrive until none arrived for the whole window.
+func drainEvents(events <-chan Event, window time.Duration) []Event {
+	var drained []Event
+	for {
+		select {
+		case event := <-events:
+			drained = append(drained, event)
+		case <-time.After(window):
+			return drained
+		}
+	}
+}
+
+func TestFile_Watch(t *testing.T) {
+	dir := Open(".tests/watch")
+	if err := dir.DeleteRecursively(); err != nil {
+		t.Fatal("failed to clean up watch test directory: ", err)
+	}
+	if err := Open(".tests/watch/existing.txt").Overwrite("hello world"); err != nil {
+		t.Fatal("failed to create watch test directory: ", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := dir.Watch(ctx, WithDebounce(50*time.Millisecond))
+	if err != nil {
+		t.Fatal("failed to watch test directory: ", err)
+	}
+
+	if err := Open(".tests/watch/nested/created.txt").Overwrite("hello world"); err != nil {
+		t.Fatal("failed to write to watched directory: ", err)
+	}
+	expectEvent(t, events, Created, ".tests/watch/nested/created.txt")
+
+	// an atomic save writes a temporary file and renames it over the original file.
+	if err := Open(".tests/watch/.existing.txt.swp").Overwrite("hello there"); err != nil {
+		t.Fatal("failed to write to watched directory: ", err)
+	}
+	if err := Open(".tests/watch/.existing.txt.swp").Rename("existing.txt"); err != nil {
+		t.Fatal("failed to rename in watched directory: ", err)
+	}
+	// the writes and the rename are debounced into a single event for the original file.
+	var saved []Event
+	for _, event := range drainEvents(events, time.Second) {
+		if filepath.ToSlash(event.File.Path()) == ".tests/watch/existing.txt" {
+			saved = append(saved, event)
+		}
+	}
+	if len(saved) != 1 || saved[0].Kind != Modified {
+		t.Fatal("expected exactly one modified event for the atomic save, got ", saved)
+	}
+
+	if err := Open(".tests/watch/nested/created.txt").Delete(); err != nil {
+		t.Fatal("failed to delete in watched directory: ", err)
+	}
+	expectEvent(t, events, Removed, ".tests/watch/nested/created.txt")
+
+	cancel()
+	for range events {
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	events, err = dir.Watch(ctx, WithPolling(20*time.Millisecond), WithDebounce(0))
+	if err != nil {
+		t.Fatal("failed to watch test directory: ", err)
+	}
+	if err := Open(".tests/watch/polled.txt").Overwrite("hello world"); err != nil {
+		t.Fatal("failed to write to watched directory: ", err)
+	}
+	expectEvent(t, events, Created, ".tests/watch/polled.txt")
+	if err := Open(".tests/watch/polled.txt").Delete(); err != nil {
+		t.Fatal("failed to delete in watched directory: ", err)
+	}
+	expectEvent(t, events, Removed, ".tests/watch/polled.txt")
+}
+
+func TestFile_WatchFile(t *testing.T) {
+	file := Open(".tests/watch-file/saved.txt")
+	if err := file.Overwrite("hello world"); err != nil {
+		t.Fatal("failed to create watched file: ", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := file.Watch(ctx, WithDebounce(50*time.Millisecond))
+	if err != nil {
+		t.Fatal("failed to watch test file: ", err)
+	}
+
+	// an atomic save replaces the watched file, which results in a single modified event.
+	if err := Open(".tests/watch-file/.saved.txt.swp").Overwrite("hello there"); err != nil {
+		t.Fatal("failed to write temporary file: ", err)
+	}
+	if err := Open(".tests/watch-file/.saved.txt.swp").Rename("saved.txt"); err != nil {
+		t.Fatal("failed to rename over watched file: ", err)
+	}
+	saved := drainEvents(events, time.Second)
+	if len(saved) != 1 || saved[0].Kind != Modified || filepath.ToSlash(saved[0].File.Path()) != ".tests/watch-file/saved.txt" {
+		t.Fatal("expected exactly one modified event for the atomic save, got ", saved)
+	}
+
+	// the watch survives the file being replaced.
+	if err := file.Write("!"); err != nil {
+		t.Fatal("failed to write to watched file: ", err)
+	}
+	expectEvent(t, events, Modified, ".tests/watch-file/saved.txt")
+}
+
+func TestScratch(t *testing.T) {
+	var scratch *File
+	if err := Scratch(func(dir *File) error {
+		scratch = dir
+		file := Open(filepath.Join(dir.Path(), "nested", "scratch.txt"))
+		if err := file.Overwrite("hello world"); err != nil {
+			return err
+		}
+		text, err := file.Text()
+		if err != nil {
+			return err
+		}
+		if text != "hello world" {
+			t.Fatal("scratch file does not match expected result, got '", text, "' instead of 'hello world'")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("failed to use scratch directory: ", err)
+	}
+	if _, err := os.Stat(scratch.Path()); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("scratch directory was not removed: ", err)
+	}
+
+	file, err := TempFile("siopao-*.txt")
+	if err != nil {
+		t.Fatal("failed to create temporary file: ", err)
+	}
+	if err := file.Write("hello world"); err != nil {
+		t.Fatal("failed to write to temporary file: ", err)
+	}
+	if err := file.Cleanup(); err != nil {
+		t.Fatal("failed to clean up temporary file: ", err)
+	}
+}
+
+func TestOpenFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"hello.txt":        {Data: []byte("hello world")},
+		"nested/hello.txt": {Data: []byte("hello world")},
+	}
+
+	file := OpenFS(fsys, "hello.txt")
+	text, err := file.Text()
+	if err != nil {
+		t.Fatal("failed to read from io/fs.FS: ", err)
+	}
+	if text != "hello world" {
+		t.Fatal("file does not match expected result, got '", text, "' instead of 'hello world'")
+	}
+	checksum, err := file.Checksum(Sha256Checksum)
+	if err != nil {
+		t.Fatal("failed to checksum file from io/fs.FS: ", err)
+	}
+	expected, err := Open(".tests/write-01.txt").Checksum(Sha256Checksum)
+	if err != nil {
+		t.Fatal("failed to checksum test text file: ", err)
+	}
+	if checksum != expected {
+		t.Fatal("checksum of io/fs.FS file does not match the checksum of the same contents on disk")
+	}
+	if err := file.Overwrite("hello there"); !errors.Is(err, ErrReadOnly) {
+		t.Fatal("writing to io/fs.FS did not fail with ErrReadOnly: ", err)
+	}
+
+	var visited []string
+	if err := OpenFS(fsys, ".").Walk(func(file *File) error {
+		visited = append(visited, filepath.ToSlash(file.Path()))
+		return nil
+	}); err != nil {
+		t.Fatal("failed to walk io/fs.FS: ", err)
+	}
+	if strings.Join(visited, ",") != "hello.txt,nested,nested/hello.txt" {
+		t.Fatal("walk of io/fs.FS visited ", visited)
+	}
+}
+
+func TestFile_FS(t *testing.T) {
+	for _, path := range []string{"a.txt", "nested/b.txt"} {
+		if err := Open(".tests/fs/" + path).Overwrite("hello world"); err != nil {
+			t.Fatal("failed to create fs test tree: ", err)
+		}
+	}
+	if err := fstest.TestFS(FS(".tests/fs"), "a.txt", "nested/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRoot(t *testing.T) {
+	if err := Open(".tests/root/inside/hello.txt").Overwrite("hello world"); err != nil {
+		t.Fatal("failed to create root test directory: ", err)
+	}
+	if err := Open(".tests/outside.txt").Overwrite("hello world"); err != nil {
+		t.Fatal("failed to create root test directory: ", err)
+	}
+	_ = os.Remove(".tests/root/escape")
+	if err := os.Symlink("../outside.txt", ".tests/root/escape"); err != nil {
+		t.Fatal("failed to create symlink: ", err)
+	}
+	_ = os.Remove(".tests/root/link")
+	if err := os.Symlink("inside", ".tests/root/link"); err != nil {
+		t.Fatal("failed to create symlink: ", err)
+	}
+
+	root, err := Root(".tests/root")
+	if err != nil {
+		t.Fatal("failed to open root: ", err)
+	}
+	text, err := root.Join("link", "hello.txt").Text()
+	if err != nil {
+		t.Fatal("failed to read through symlink inside root: ", err)
+	}
+	if text != "hello world" {
+		t.Fatal("file does not match expected result, got '", text, "' instead of 'hello world'")
+	}
+
+	for _, path := range []string{"../outside.txt", "inside/../../outside.txt", "escape"} {
+		if _, err := root.Join(path).Text(); !errors.Is(err, ErrEscapesRoot) {
+			t.Fatal("reading ", path, " did not fail with ErrEscapesRoot: ", err)
+		}
+	}
+	if _, err := root.derive("/etc/passwd").Text(); !errors.Is(err, ErrEscapesRoot) {
+		t.Fatal("reading an absolute path did not fail with ErrEscapesRoot: ", err)
+	}
+	if err := root.Join("inside/hello.txt").Copy("../copied.txt"); !errors.Is(err, ErrEscapesRoot) {
+		t.Fatal("copying outside of root did not fail with ErrEscapesRoot: ", err)
+	}
+	if err := root.Join("inside/hello.txt").MoveTo("../.."); !errors.Is(err, ErrEscapesRoot) {
+		t.Fatal("moving outside of root did not fail with ErrEscapesRoot: ", err)
+	}
+	if err := root.Join("inside/hello.txt").Copy("copied/hello.txt"); err != nil {
+		t.Fatal("failed to copy inside root: ", err)
+	}
+
+	var visited []string
+	if err := root.Recurse(true, func(file *File) {
+		visited = append(visited, filepath.ToSlash(file.Path()))
+	}); err != nil {
+		t.Fatal("failed to recurse root: ", err)
+	}
+	if strings.Join(visited, ",") != "copied,copied/hello.txt,escape,inside,inside/hello.txt,link" {
+		t.Fatal("recurse of root visited ", visited)
+	}
+
+	// removing a symlink removes the link itself and not what it points to.
+	if err := root.Join("escape").Delete(); err != nil {
+		t.Fatal("failed to delete symlink inside root: ", err)
+	}
+	if _, err := os.Stat(".tests/outside.txt"); err != nil {
+		t.Fatal("deleting the symlink deleted what it points to: ", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	file := Open(".tests/write-01.txt")
+	if err := file.Walk(func(file *File) error { return nil }); !errors.Is(err, ErrNotDirectory) {
+		t.Fatal("walking a file did not fail with ErrNotDirectory: ", err)
+	}
+	if _, err := file.Checksum("crc0"); !errors.Is(err, ErrUnsupportedChecksum) {
+		t.Fatal("unknown checksum did not fail with ErrUnsupportedChecksum: ", err)
+	}
+	var hello Hello
+	if err := Open(".tests/write-02.json").Json(hello); !errors.Is(err, ErrNonPointer) {
+		t.Fatal("unmarshalling into a value did not fail with ErrNonPointer: ", err)
+	}
+
+	_, err := Open(".tests/missing.txt").Text()
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("reading a missing file did not fail with fs.ErrNotExist: ", err)
+	}
+	var opErr *OpError
+	if !errors.As(err, &opErr) {
+		t.Fatal("reading a missing file did not fail with an OpError: ", err)
+	}
+	if opErr.Op != "read" || opErr.Path != ".tests/missing.txt" {
+		t.Fatal("unexpected operation or path in OpError: ", opErr)
+	}
+}
+
+// faultyFilesystem is the filesystem of the operating system, but every read fails with the error once the given
+// amount of bytes are read, or opening fails entirely when the amount is negative.
+type faultyFilesystem struct {
+	OSFilesystem
+	failAfter int64
+	err       error
+}
+
+func (fsys faultyFilesystem) Open(name string) (fs.File, error) {
+	if fsys.failAfter < 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fsys.err}
+	}
+	f, err := fsys.OSFilesystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &faultyFile{File: f, remaining: fsys.failAfter, err: fsys.err}, nil
+}
+
+type faultyFile struct {
+	fs.File
+	remaining int64
+	err       error
+}
+
+func (f *faultyFile) Read(p []byte) (int, error) {
+	if f.remaining <= 0 {
+		return 0, f.err
+	}
+	if int64(len(p)) > f.remaining {
+		p = p[:f.remaining]
+	}
+	n, err := f.File.Read(p)
+	f.remaining -= int64(n)
+	return n, err
+}
+
+func TestFile_CopyStrategies(t *testing.T) {
+	if err := os.MkdirAll(".tests", os.ModePerm); err != nil {
+		t.Fatal("failed to create test directory: ", err)
+	}
+	sparse, err := os.Create(".tests/sparse.img")
+	if err != nil {
+		t.Fatal("failed to create sparse test file: ", err)
+	}
+	if _, err := sparse.WriteAt([]byte("hello world"), 4<<20); err != nil {
+		t.Fatal("failed to write to sparse test file: ", err)
+	}
+	if err := sparse.Truncate(8 << 20); err != nil {
+		t.Fatal("failed to extend sparse test file: ", err)
+	}
+	_ = sparse.Close()
+
+	file := Open(".tests/sparse.img")
+	expected, err := file.Checksum(XxHash64Checksum)
+	if err != nil {
+		t.Fatal("failed to checksum sparse test file: ", err)
+	}
+	strategies := map[string]CopyStrategy{
+		"stream":   StreamCopy,
+		"reflink":  ReflinkCopy,
+		"kernel":   KernelCopy,
+		"sparse":   SparseCopy,
+		"hardlink": HardlinkCopy,
+	}
+	for name, strategy := range strategies {
+		dest := ".tests/copy-" + name + ".img"
+		var last Progress
+		if err := file.Copy(dest, WithStrategy(strategy), WithProgress(func(progress Progress) { last = progress })); err != nil {
+			t.Fatal("failed to copy with ", name, ": ", err)
+		}
+		sum, err := Open(dest).Checksum(XxHash64Checksum)
+		if err != nil {
+			t.Fatal("failed to checksum copy of ", name, ": ", err)
+		}
+		if sum != expected {
+			t.Fatal("copy with ", name, " does not match the source")
+		}
+		if last.Done != 8<<20 {
+			t.Fatal("unexpected progress after copying with ", name, ": ", last)
+		}
+	}
+
+	source, _ := os.Stat(".tests/sparse.img")
+	link, _ := os.Stat(".tests/copy-hardlink.img")
+	if !os.SameFile(source, link) {
+		t.Fatal("expected hardlink copy to be the same file as the source")
+	}
+}
+
+func TestFile_Policies(t *testing.T) {
+	if err := os.RemoveAll(".tests/policy"); err != nil {
+		t.Fatal("failed to clean test directory: ", err)
+	}
+	source := Open(".tests/policy/source.txt")
+	if err := source.Overwrite("new"); err != nil {
+		t.Fatal("failed to write to test file: ", err)
+	}
+	existing := Open(".tests/policy/existing.txt")
+	if err := existing.Overwrite("old"); err != nil {
+		t.Fatal("failed to write to test file: ", err)
+	}
+	expect := func(path string, expected string) {
+		t.Helper()
+		text, err := Open(path).Text()
+		if err != nil || text != expected {
+			t.Fatal("expected ", path, " to contain ", expected, ", got ", text, " and ", err)
+		}
+	}
+
+	if err := source.Copy(".tests/policy/existing.txt", WithPolicy(FailIfExists)); !errors.Is(err, ErrExists) {
+		t.Fatal("expected copy to fail with ErrExists, got: ", err)
+	}
+	if err := source.Move(".tests/policy/existing.txt", WithPolicy(FailIfExists)); !errors.Is(err, fs.ErrExist) {
+		t.Fatal("expected move to fail with ErrExists, got: ", err)
+	}
+	if err := source.Copy(".tests/policy/existing.txt", WithPolicy(SkipExisting)); err != nil {
+		t.Fatal("failed to skip copy: ", err)
+	}
+	expect(".tests/policy/existing.txt", "old")
+
+	for i := 1; i <= 2; i++ {
+		if err := source.Copy(".tests/policy/existing.txt", WithPolicy(KeepBoth)); err != nil {
+			t.Fatal("failed to copy while keeping both: ", err)
+		}
+		expect(".tests/policy/existing ("+strconv.Itoa(i)+").txt", "new")
+	}
+	expect(".tests/policy/existing.txt", "old")
+
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(".tests/policy/source.txt", past, past); err != nil {
+		t.Fatal("failed to chtimes test file: ", err)
+	}
+	if err := source.Move(".tests/policy/existing.txt", WithPolicy(ReplaceIfNewer)); err != nil {
+		t.Fatal("failed to skip older move: ", err)
+	}
+	expect(".tests/policy/existing.txt", "old")
+	expect(".tests/policy/source.txt", "new")
+
+	if err := os.Chtimes(".tests/policy/existing.txt", past.Add(-time.Hour), past.Add(-time.Hour)); err != nil {
+		t.Fatal("failed to chtimes test file: ", err)
+	}
+	if err := source.Rename("existing.txt", WithPolicy(ReplaceIfNewer)); err != nil {
+		t.Fatal("failed to replace older file: ", err)
+	}
+	expect(".tests/policy/existing.txt", "new")
+
+	if err := source.Overwrite("hashed"); err != nil {
+		t.Fatal("failed to write to test file: ", err)
+	}
+	hashed := "1a06df824ed741b53c785079a6347f00eec5af82f9850775409ca69dff4068a6"
+	// path is the file that is expected to contain the content after copying over the existing file.
+	expectHash := func(policy OverwritePolicy, expected string, path string, content string) {
+		t.Helper()
+		sum, err := source.CopyWithHash(Sha256Checksum, ".tests/policy/existing.txt", WithPolicy(policy))
+		if err != nil {
+			t.Fatal("failed to copy with hash: ", err)
+		}
+		if (sum == nil && expected != "") || (sum != nil && *sum != expected) {
+			t.Fatal("unexpected hash of copy with policy ", policy, ": ", sum)
+		}
+		expect(path, content)
+	}
+	if _, err := source.CopyWithHash(Sha256Checksum, ".tests/policy/existing.txt", WithPolicy(FailIfExists)); !errors.Is(err, ErrExists) {
+		t.Fatal("expected copy with hash to fail with ErrExists, got: ", err)
+	}
+	expect(".tests/policy/existing.txt", "new")
+	expectHash(SkipExisting, "", ".tests/policy/existing.txt", "new")
+	expectHash(KeepBoth, hashed, ".tests/policy/existing (3).txt", "hashed")
+	expect(".tests/policy/existing.txt", "new")
+
+	if err := os.Chtimes(".tests/policy/source.txt", past, past); err != nil {
+		t.Fatal("failed to chtimes test file: ", err)
+	}
+	expectHash(ReplaceIfNewer, "", ".tests/policy/existing.txt", "new")
+	expectHash(ReplaceExisting, hashed, ".tests/policy/existing.txt", "hashed")
+}
+
+// racyFilesystem is the filesystem of the operating system, but the file at the path is created, by someone else, right
+// after the first time it is found to not exist.
+type racyFilesystem struct {
+	OSFilesystem
+	path    string
+	created *bool
+}
+
+func (fsys racyFilesystem) Stat(name string) (fs.FileInfo, error) {
+	info, err := fsys.OSFilesystem.Stat(name)
+	if errors.Is(err, fs.ErrNotExist) && name == fsys.path && !*fsys.created {
+		*fsys.created = true
+		if err := os.WriteFile(name, []byte("late"), 0644); err != nil {
+			return nil, err
+		}
+	}
+	return info, err
+}
+
+func TestFile_MoveRace(t *testing.T) {
+	if err := os.RemoveAll(".tests/race"); err != nil {
+		t.Fatal("failed to clean test directory: ", err)
+	}
+	if err := os.MkdirAll(".tests/race", os.ModePerm); err != nil {
+		t.Fatal("failed to create test directory: ", err)
+	}
+	for _, policy := range []OverwritePolicy{FailIfExists, KeepBoth} {
+		if err := os.WriteFile(".tests/race/source.txt", []byte("source"), 0644); err != nil {
+			t.Fatal("failed to write test file: ", err)
+		}
+		if err := os.RemoveAll(".tests/race/late.txt"); err != nil {
+			t.Fatal("failed to clean test file: ", err)
+		}
+		created := false
+		source := OpenFS(racyFilesystem{path: ".tests/race/late.txt", created: &created}, ".tests/race/source.txt")
+		err := source.Move(".tests/race/late.txt", WithPolicy(policy))
+		if policy == FailIfExists && !errors.Is(err, ErrExists) {
+			t.Fatal("expected move to fail with ErrExists, got: ", err)
+		}
+		if policy == KeepBoth && err != nil {
+			t.Fatal("failed to move while keeping both: ", err)
+		}
+		if !created {
+			t.Fatal("expected the destination to be created while moving")
+		}
+		if text, err := os.ReadFile(".tests/race/late.txt"); err != nil || string(text) != "late" {
+			t.Fatal("expected the late destination to be kept, got ", string(text), " and ", err)
+		}
+	}
+	if text, err := os.ReadFile(".tests/race/late (1).txt"); err != nil || string(text) != "source" {
+		t.Fatal("expected the source to be kept next to the late destination, got ", string(text), " and ", err)
+	}
+}
+
+func TestFile_Symlinks(t *testing.T) {
+	if err := os.RemoveAll(".tests/links"); err != nil {
+		t.Fatal("failed to clean test directory: ", err)
+	}
+	if err := Open(".tests/links/dir/a.txt").Overwrite("hello world"); err != nil {
+		t.Fatal("failed to write to test file: ", err)
+	}
+
+	link := Open(".tests/links/dir/b.txt")
+	if err := link.Symlink("a.txt"); err != nil {
+		t.Fatal("failed to create symlink: ", err)
+	}
+	if target, err := link.Readlink(); err != nil || target != "a.txt" {
+		t.Fatal("unexpected target of symlink: ", target, err)
+	}
+	if isSymlink, err := link.IsSymlink(); err != nil || !isSymlink {
+		t.Fatal("expected file to be a symlink: ", err)
+	}
+	resolved, err := link.Resolve()
+	if err != nil {
+		t.Fatal("failed to resolve symlink: ", err)
+	}
+	expected, _ := filepath.Abs(".tests/links/dir/a.txt")
+	if resolved.Path() != expected {
+		t.Fatal("expected symlink to resolve to ", expected, ", got ", resolved.Path())
+	}
+
+	root, err := Root(".tests/links")
+	if err != nil {
+		t.Fatal("failed to open root: ", err)
+	}
+	if resolved, err := root.Join("dir", "b.txt").Resolve(); err != nil || resolved.Path() != filepath.Join("dir", "a.txt") {
+		t.Fatal("expected symlink to resolve inside the root, got ", resolved, err)
+	}
+
+	if err := Open(".tests/links/dir/c.txt").Hardlink(".tests/links/dir/a.txt"); err != nil {
+		t.Fatal("failed to create hardlink: ", err)
+	}
+	if text, err := Open(".tests/links/dir/c.txt").Text(); err != nil || text != "hello world" {
+		t.Fatal("unexpected contents of hardlink: ", text, err)
+	}
+
+	// loop points back to the directory itself, which would walk forever when the cycle is not broken.
+	if err := Open(".tests/links/dir/loop").Symlink("."); err != nil {
+		t.Fatal("failed to create cyclic symlink: ", err)
+	}
+	if err := Open(".tests/links/other/d.txt").Overwrite("hello world"); err != nil {
+		t.Fatal("failed to write to test file: ", err)
+	}
+	if err := Open(".tests/links/dir/other").Symlink("../other"); err != nil {
+		t.Fatal("failed to create directory symlink: ", err)
+	}
+
+	walk := func(policy SymlinkPolicy) string {
+		var visited []string
+		if err := Open(".tests/links/dir").Recurse(true, func(file *File) {
+			rel, _ := filepath.Rel(".tests/links/dir", file.Path())
+			visited = append(visited, filepath.ToSlash(rel))
+		}, WithSymlinks(policy)); err != nil {
+			t.Fatal("failed to walk with symlinks: ", err)
+		}
+		return strings.Join(visited, ",")
+	}
+	if visited := walk(ReportSymlinks); visited != "a.txt,b.txt,c.txt,loop,other" {
+		t.Fatal("unexpected files when reporting symlinks: ", visited)
+	}
+	if visited := walk(SkipSymlinks); visited != "a.txt,c.txt" {
+		t.Fatal("unexpected files when skipping symlinks: ", visited)
+	}
+	if visited := walk(FollowSymlinks); visited != "a.txt,b.txt,c.txt,loop,other,other/d.txt" {
+		t.Fatal("unexpected files when following symlinks: ", visited)
+	}
+}
+
+func TestTrash(t *testing.T) {
+	if err := os.RemoveAll(".tests/trash"); err != nil {
+		t.Fatal("failed to clean test directory: ", err)
+	}
+	data := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", data)
+
+	first := Open(".tests/trash/work/report.txt")
+	if err := first.Overwrite("first"); err != nil {
+		t.Fatal("failed to write to test file: ", err)
+	}
+	if _, err := first.Trash(); err != nil {
+		t.Fatal("failed to trash test file: ", err)
+	}
+	if err := first.Overwrite("second"); err != nil {
+		t.Fatal("failed to write to test file: ", err)
+	}
+	item, err := first.Trash()
+	if err != nil {
+		t.Fatal("failed to trash test file: ", err)
+	}
+	if item.Name != "report (1).txt" {
+		t.Fatal("expected second item to have a numbered name, got ", item.Name)
+	}
+
+	can, err := Trash()
+	if err != nil {
+		t.Fatal("failed to get trash: ", err)
+	}
+	if can.Path() != filepath.Join(data, "Trash") {
+		t.Fatal("unexpected trash path: ", can.Path())
+	}
+	info, err := Open(filepath.Join(can.Path(), "info", "report.txt.trashinfo")).Text()
+	if err != nil || !strings.HasPrefix(info, "[Trash Info]\nPath=/") || !strings.Contains(info, "DeletionDate=") {
+		t.Fatal("unexpected trashinfo: ", info, err)
+	}
+
+	items, err := can.List()
+	if err != nil {
+		t.Fatal("failed to list trash: ", err)
+	}
+	if len(items) != 2 {
+		t.Fatal("expected two items in the trash, got ", items)
+	}
+	expected, _ := filepath.Abs(".tests/trash/work/report.txt")
+	if items[0].OriginalPath != expected {
+		t.Fatal("unexpected original path: ", items[0].OriginalPath)
+	}
+
+	restored, err := can.Restore("report (1).txt")
+	if err != nil {
+		t.Fatal("failed to restore item: ", err)
+	}
+	if text, err := restored.Text(); err != nil || text != "second" {
+		t.Fatal("unexpected contents of restored item: ", text, err)
+	}
+	if _, err := can.Restore("report.txt"); !errors.Is(err, ErrExists) {
+		t.Fatal("expected restore over an existing file to fail with ErrExists, got: ", err)
+	}
+	if _, err := can.Restore("../info/report.txt"); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatal("expected restore of an invalid name to fail, got: ", err)
+	}
+
+	if err := can.Empty(time.Hour); err != nil {
+		t.Fatal("failed to empty trash: ", err)
+	}
+	if items, _ := can.List(); len(items) != 1 {
+		t.Fatal("expected recent items to be kept, got ", items)
+	}
+	if err := can.Empty(0); err != nil {
+		t.Fatal("failed to empty trash: ", err)
+	}
+	if items, _ := can.List(); len(items) != 0 {
+		t.Fatal("expected trash to be empty, got ", items)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(can.Path(), "files")); len(entries) != 0 {
+		t.Fatal("expected trashed files to be deleted, got ", entries)
+	}
+
+	// a relative XDG_DATA_HOME is ignored.
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal("failed to get home directory: ", err)
+	}
+	t.Setenv("XDG_DATA_HOME", ".tests/trash/data")
+	if can, err := Trash(); err != nil || can.Path() != filepath.Join(home, ".local", "share", "Trash") {
+		t.Fatal("expected a relative XDG_DATA_HOME to be ignored, got ", can, " and ", err)
+	}
+}
+
+func TestFile_DeleteGuards(t *testing.T) {
+	if err := Open("").DeleteRecursively(); !errors.Is(err, ErrUnsafeDelete) {
+		t.Fatal("expected deleting an empty path to fail with ErrUnsafeDelete, got: ", err)
+	}
+	// dry runs are used for the dangerous paths, so nothing is deleted even when the guards are broken.
+	home, _ := os.UserHomeDir()
+	for _, path := range []string{"/", home, filepath.Dir(home)} {
+		if _, err := Open(path).DeleteRecursivelyDryRun(WithMaxFiles(1)); !errors.Is(err, ErrUnsafeDelete) {
+			t.Fatal("expected deleting ", path, " to fail with ErrUnsafeDelete, got: ", err)
+		}
+	}
+
+	if err := os.RemoveAll(".tests/delete"); err != nil {
+		t.Fatal("failed to clean test directory: ", err)
+	}
+	for _, path := range []string{"a.txt", "b.txt", "nested/c.txt"} {
+		if err := Open(filepath.Join(".tests/delete", path)).Overwrite("hello world"); err != nil {
+			t.Fatal("failed to write to test file: ", err)
+		}
+	}
+	dir := Open(".tests/delete")
+	files, err := dir.DeleteRecursivelyDryRun()
+	if err != nil {
+		t.Fatal("failed to dry run delete: ", err)
+	}
+	if len(files) != 5 || files[0].Path() != ".tests/delete" {
+		t.Fatal("unexpected files in dry run: ", len(files))
+	}
+	if err := dir.DeleteRecursively(WithMaxFiles(2)); !errors.Is(err, ErrUnsafeDelete) {
+		t.Fatal("expected delete over the file limit to fail, got: ", err)
+	}
+	if err := dir.DeleteRecursively(WithMaxBytes(32)); !errors.Is(err, ErrUnsafeDelete) {
+		t.Fatal("expected delete over the byte limit to fail, got: ", err)
+	}
+	if _, err := os.Stat(".tests/delete/nested/c.txt"); err != nil {
+		t.Fatal("expected nothing to be deleted over the limits: ", err)
+	}
+
+	secret := Open(".tests/delete/a.txt")
+	if err := secret.Shred(2); err != nil {
+		t.Fatal("failed to shred test file: ", err)
+	}
+	if _, err := os.Stat(".tests/delete/a.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("expected shredded file to be deleted: ", err)
+	}
+	if err := Open(".tests/delete/nested").Shred(1); !errors.Is(err, syscall.EISDIR) {
+		t.Fatal("expected shredding a directory to fail, got: ", err)
+	}
+
+	if err := dir.DeleteRecursively(WithMaxFiles(2), WithMaxBytes(22)); err != nil {
+		t.Fatal("failed to delete within the limits: ", err)
+	}
+}
+
+func TestFile_RotatingWriter(t *testing.T) {
+	if err := os.RemoveAll(".tests/rotating"); err != nil {
+		t.Fatal("failed to clean test directory: ", err)
+	}
+	writer, err := Open(".tests/rotating/app.log").RotatingWriter()
+	if err != nil {
+		t.Fatal("failed to open rotating writer: ", err)
+	}
+	writer.RotateAtSize(64).NameWith(streaming.SequenceNaming).KeepFiles(3).AlwaysAppendNewLine()
+	record := strings.Repeat("x", 19)
+	for i := 0; i < 30; i++ {
+		if err := writer.Write(record); err != nil {
+			t.Fatal("failed to write to rotating writer: ", err)
+		}
+	}
+	if err := writer.End(); err != nil {
+		t.Fatal("failed to end rotating writer: ", err)
+	}
+
+	entries, err := os.ReadDir(".tests/rotating")
+	if err != nil {
+		t.Fatal("failed to read rotating directory: ", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	// three records of 20 bytes fit in 64 bytes, so 30 records make 10 files, of which the last 3 rotated are kept.
+	if strings.Join(names, ",") != "app.7.log,app.8.log,app.9.log,app.log" {
+		t.Fatal("unexpected rotated files: ", names)
+	}
+	for _, name := range names {
+		text, err := Open(filepath.Join(".tests/rotating", name)).Text()
+		if err != nil || text != strings.Repeat(record+"\n", 3) {
+			t.Fatal("expected ", name, " to contain three whole records, got ", text, err)
+		}
+	}
+
+	writer, err = Open(".tests/rotating/app.log").RotatingWriter()
+	if err != nil {
+		t.Fatal("failed to open rotating writer: ", err)
+	}
+	writer.RotateAtLines(2).Compress()
+	for i := 0; i < 6; i++ {
+		if err := writer.Write(record + "\n"); err != nil {
+			t.Fatal("failed to write to rotating writer: ", err)
+		}
+	}
+	if err := writer.End(); err != nil {
+		t.Fatal("failed to end rotating writer: ", err)
+	}
+	compressed, _ := filepath.Glob(".tests/rotating/app-*.log.gz")
+	if len(compressed) != 2 {
+		t.Fatal("expected two compressed timestamped files, got ", compressed)
+	}
+
+	if _, err := OpenFS(fstest.MapFS{}, "app.log").RotatingWriter(); !errors.Is(err, ErrUnsupported) {
+		t.Fatal("expected rotating writer on another filesystem to fail, got: ", err)
+	}
+}
+
+// crossDeviceFilesystem is the filesystem of the operating system, but every rename between two directories fails
+// as if the destination was on another device, and the unreadable file, if any, cannot be opened.
+type crossDeviceFilesystem struct {
+	OSFilesystem
+	unreadable string
+}
+
+func (fsys crossDeviceFilesystem) Rename(oldpath, newpath string) error {
+	if filepath.Dir(oldpath) != filepath.Dir(newpath) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+	return fsys.OSFilesystem.Rename(oldpath, newpath)
+}
+
+func (fsys crossDeviceFilesystem) Open(name string) (fs.File, error) {
+	if fsys.unreadable != "" && filepath.Clean(name) == filepath.Clean(fsys.unreadable) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return fsys.OSFilesystem.Open(name)
+}
+
+func TestFile_MoveAcrossDevices(t *testing.T) {
+	if err := os.RemoveAll(".tests/xdev"); err != nil {
+		t.Fatal("failed to clean test directory: ", err)
+	}
+	source := OpenFS(crossDeviceFilesystem{}, ".tests/xdev/source.txt")
+	if err := source.Overwrite("hello world"); err != nil {
+		t.Fatal("failed to write to test file: ", err)
+	}
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chmod(".tests/xdev/source.txt", 0640); err != nil {
+		t.Fatal("failed to chmod test file: ", err)
+	}
+	if err := os.Chtimes(".tests/xdev/source.txt", modTime, modTime); err != nil {
+		t.Fatal("failed to chtimes test file: ", err)
+	}
+
+	if err := source.Move(".tests/xdev/atomic/atomic.txt", RequireAtomic()); !errors.Is(err, syscall.EXDEV) {
+		t.Fatal("expected atomic move to fail with EXDEV, got: ", err)
+	}
+
+	if err := source.Move(".tests/xdev/moved/dest.txt"); err != nil {
+		t.Fatal("failed to move test file across devices: ", err)
+	}
+	if _, err := os.Stat(".tests/xdev/source.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("expected source to be deleted after moving: ", err)
+	}
+	info, err := os.Stat(".tests/xdev/moved/dest.txt")
+	if err != nil {
+		t.Fatal("failed to stat moved file: ", err)
+	}
+	if info.Mode().Perm() != 0640 || !info.ModTime().Equal(modTime) {
+		t.Fatal("expected permissions and timestamps to be kept, got ", info.Mode(), " and ", info.ModTime())
+	}
+
+	if err := OpenFS(crossDeviceFilesystem{}, ".tests/xdev/moved").MoveTo(".tests/xdev/dir"); err != nil {
+		t.Fatal("failed to move test directory across devices: ", err)
+	}
+	text, err := Open(".tests/xdev/dir/moved/dest.txt").Text()
+	if err != nil || text != "hello world" {
+		t.Fatal("expected moved directory to keep its contents, got ", text, " and ", err)
+	}
+	if _, err := os.Stat(".tests/xdev/moved"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("expected source directory to be deleted after moving: ", err)
+	}
+
+	// a failed move leaves an existing destination as it was.
+	if err := Open(".tests/xdev/unreadable.txt").Overwrite("hello world"); err != nil {
+		t.Fatal("failed to write to test file: ", err)
+	}
+	if err := Open(".tests/xdev/dir/existing.txt").Overwrite("keep me"); err != nil {
+		t.Fatal("failed to write to test file: ", err)
+	}
+	unreadable := OpenFS(crossDeviceFilesystem{unreadable: ".tests/xdev/unreadable.txt"}, ".tests/xdev/unreadable.txt")
+	if err := unreadable.Move(".tests/xdev/dir/existing.txt"); !errors.Is(err, fs.ErrPermission) {
+		t.Fatal("expected move of an unreadable file to fail, got: ", err)
+	}
+	if text, err := Open(".tests/xdev/dir/existing.txt").Text(); err != nil || text != "keep me" {
+		t.Fatal("expected existing destination to be untouched, got ", text, " and ", err)
+	}
+	if entries, err := os.ReadDir(".tests/xdev/dir"); err != nil || len(entries) != 2 {
+		t.Fatal("expected no temporary files to be left behind, got ", entries, " and ", err)
+	}
+
+	// symbolic links inside a directory are recreated instead of failing the move.
+	if err := Open(".tests/xdev/linked/target.txt").Overwrite("hello world"); err != nil {
+		t.Fatal("failed to write to test file: ", err)
+	}
+	if err := os.Symlink("target.txt", ".tests/xdev/linked/link.txt"); err != nil {
+		t.Skip("symbolic links are not supported: ", err)
+	}
+	if err := OpenFS(crossDeviceFilesystem{}, ".tests/xdev/linked").MoveTo(".tests/xdev/dir"); err != nil {
+		t.Fatal("failed to move directory with a symbolic link across devices: ", err)
+	}
+	if target, err := os.Readlink(".tests/xdev/dir/linked/link.txt"); err != nil || target != "target.txt" {
+		t.Fatal("expected symbolic link to be recreated, got ", target, " and ", err)
+	}
+	if text, err := Open(".tests/xdev/dir/linked/link.txt").Text(); err != nil || text != "hello world" {
+		t.Fatal("expected symbolic link to resolve, got ", text, " and ", err)
+	}
+}
+
+func TestFile_ReadErrors(t *testing.T) {
+	if err := Open(".tests/write-02.json").Overwrite(Hello{"hello world"}); err != nil {
+		t.Fatal("failed to write to test json file: ", err)
+	}
+
+	apis := map[string]func(file *File) error{
+		"Bytes": func(file *File) error {
+			_, err := file.Bytes()
+			return err
+		},
+		"Text": func(file *File) error {
+			_, err := file.Text()
+			return err
+		},
+		"Unmarshal": func(file *File) error {
+			var hello Hello
+			return file.Unmarshal(paopao.Unmarshal, &hello)
+		},
+		"Json": func(file *File) error {
+			var hello Hello
+			return file.Json(&hello)
+		},
+	}
+
+	midRead := errors.New("failed in the middle of reading")
+	cases := map[string]struct {
+		file     *File
+		expected error
+	}{
+		"missing":    {file: Open(".tests/missing.txt"), expected: fs.ErrNotExist},
+		"unreadable": {file: OpenFS(faultyFilesystem{failAfter: -1, err: fs.ErrPermission}, ".tests/write-02.json"), expected: fs.ErrPermission},
+		"mid-read":   {file: OpenFS(faultyFilesystem{failAfter: 4, err: midRead}, ".tests/write-02.json"), expected: midRead},
+		"directory":  {file: Open(".tests")},
+	}
+
+	for api, fn := range apis {
+		for name, c := range cases {
+			err := fn(c.file)
+			if err == nil {
+				t.Fatal(api, " did not fail for the ", name, " file")
+			}
+			if c.expected != nil && !errors.Is(err, c.expected) {
+				t.Fatal(api, " failed with an unexpected error for the ", name, " file: ", err)
+			}
+			var opErr *OpError
+			if !errors.As(err, &opErr) {
+				t.Fatal(api, " did not fail with an OpError for the ", name, " file: ", err)
+			}
+		}
+		if err := fn(Open(".tests/write-02.json")); err != nil {
+			t.Fatal(api, " failed to read the test json file: ", err)
+		}
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	file := Open(".tests/concurrency-01.json")
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+
+	written := make(chan int, 1)
+	go func() {
+		defer wg.Done()
+		i := 0
+		for i < 1000 {
+			i++
+			if err := file.Overwrite("hello " + strconv.Itoa(i)); err != nil {
+				t.Error(err)
+			}
+
+			written <- i
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			i := <-written
+			if i == 1000 {
+				fmt.Println("written ", i)
+				text, err := file.Text()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				fmt.Println("concurrency: ", text)
+			}
+			if i == 1000 {
+				close(written)
+				break
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func TestConcurrentWriter(t *testing.T) {
+	modes := map[string]func(writer *streaming.Writer) *streaming.Writer{
+		"concurrent": (*streaming.Writer).Concurrent,
+		"batched":    (*streaming.Writer).Batched,
+	}
+	for name, mode := range modes {
+		file := Open(".tests/concurrent-" + name + ".txt")
+		writer, err := file.WriterSize(true, 64)
+		if err != nil {
+			t.Fatal("failed to open writer: ", err)
+		}
+		mode(writer).AlwaysAppendNewLine()
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				content := strings.Repeat(strconv.Itoa(i), 100)
+				record := make([]byte, len(content))
+				for j := 0; j < 500; j++ {
+					copy(record, content)
+					if err := writer.Write(record); err != nil {
+						t.Error("failed to write to ", name, " writer: ", err)
+						return
+					}
+					// the record is reused, and stays mutated until the next write, which must not affect the queued
+					// writes of a batched writer.
+					copy(record, strings.Repeat("x", len(record)))
+					runtime.Gosched()
+				}
+			}(i)
+		}
+		wg.Wait()
+		if err := writer.End(); err != nil {
+			t.Fatal("failed to end ", name, " writer: ", err)
+		}
+
+		text, err := file.Text()
+		if err != nil {
+			t.Fatal("failed to read ", name, " file: ", err)
+		}
+		lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+		if len(lines) != 8*500 {
+			t.Fatal("expected ", 8*500, " lines from the ", name, " writer, got ", len(lines))
+		}
+		for _, line := range lines {
+			if strings.Contains(line, "x") {
+				t.Fatal("found a line that was modified after it was written to the ", name, " writer: ", line)
+			}
+			if len(line) != 100 || strings.Count(line, line[:1]) != 100 {
+				t.Fatal("found an interleaved line from the ", name, " writer: ", line)
+			}
+		}
+	}
+}
+
+// blockingWriter blocks every write until it is released, signaling when a write was entered.
+type blockingWriter struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (writer *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case writer.entered <- struct{}{}:
+	default:
+	}
+	<-writer.release
+	return len(p), nil
+}
+
+func (writer *blockingWriter) Close() error {
+	return nil
+}
+
+func TestAsyncWriter(t *testing.T) {
+	file := Open(".tests/async.txt")
+	writer, err := file.AsyncWriter(true, 16)
+	if err != nil {
+		t.Fatal("failed to open async writer: ", err)
+	}
+	writer.FlushAt(64).FlushEvery(10 * time.Millisecond)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 250; j++ {
+				if err := writer.Write(fmt.Sprintf("%d-%d\n", i, j)); err != nil {
+					t.Error("failed to write to async writer: ", err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := writer.Flush(); err != nil {
+		t.Fatal("failed to flush async writer: ", err)
+	}
+	text, err := file.Text()
+	if err != nil {
+		t.Fatal("failed to read file: ", err)
+	}
+	if lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n"); len(lines) != 1000 {
+		t.Fatal("expected 1000 lines after flushing, got ", len(lines))
+	}
+	if err := writer.End(); err != nil {
+		t.Fatal("failed to end async writer: ", err)
+	}
+	if err := writer.Write("late"); !errors.Is(err, streaming.ErrWriterEnded) {
+		t.Fatal("expected writing after end to fail, got ", err)
+	}
+
+	// a writer on a closed file fails every flush, which is reported to the callback and on end.
+	f, err := os.Create(".tests/async-closed.txt")
+	if err != nil {
+		t.Fatal("failed to create file: ", err)
+	}
+	_ = f.Close()
+	var reported atomic.Int64
+	failing := streaming.NewWriter(f).Async(1).WhenFull(streaming.FailWhenFull).OnError(func(err error) {
+		reported.Add(1)
+	})
+	if err := failing.Write("hello"); err != nil {
+		t.Fatal("failed to queue write: ", err)
+	}
+	if err := failing.End(); err == nil {
+		t.Fatal("expected end to report the flush error")
+	}
+	if reported.Load() == 0 {
+		t.Fatal("expected the error callback to be called")
+	}
+
+	// the background goroutine is stuck writing the first record, the second fills the queue and the third fails.
+	blocked := &blockingWriter{entered: make(chan struct{}, 1), release: make(chan struct{})}
+	full := streaming.NewWriterSize(blocked, 1).Async(1).WhenFull(streaming.FailWhenFull).FlushEvery(0)
+	if err := full.Write("first"); err != nil {
+		t.Fatal("failed to queue write: ", err)
+	}
+	<-blocked.entered
+	if err := full.Write("second"); err != nil {
+		t.Fatal("failed to queue write: ", err)
+	}
+	if err := full.Write("third"); !errors.Is(err, streaming.ErrQueueFull) {
+		t.Fatal("expected a full queue to fail with ErrQueueFull, got ", err)
+	}
+	close(blocked.release)
+	if err := full.End(); err != nil {
+		t.Fatal("failed to end async writer: ", err)
+	}
+
+	for _, policy := range []streaming.FullPolicy{streaming.DropOldest, streaming.DropNewest} {
+		writer, err := Open(".tests/async-drop.txt").AsyncWriter(true, 1)
+		if err != nil {
+			t.Fatal("failed to open async writer: ", err)
+		}
+		writer.WhenFull(policy)
+		for i := 0; i < 1000; i++ {
+			if err := writer.Write("x"); err != nil {
+				t.Fatal("failed to write to async writer: ", err)
+			}
+		}
+		if err := writer.End(); err != nil {
+			t.Fatal("failed to end async writer: ", err)
+		}
+		text, err := Open(".tests/async-drop.txt").Text()
+		if err != nil {
+			t.Fatal("failed to read file: ", err)
+		}
+		if int64(len(text))+writer.Dropped() != 1000 {
+			t.Fatal("expected written and dropped writes to add up, got ", len(text), " and ", writer.Dropped())
+		}
+	}
+}
+
+func TestFile_ResumableReader(t *testing.T) {
+	type event struct {
+		Id int `json:"id"`
+	}
+	file := Open(".tests/resumable.ndjson")
+	var contents strings.Builder
+	for i := 1; i <= 100; i++ {
+		contents.WriteString(fmt.Sprintf("{\"id\":%d}\r\n", i))
+	}
+	if err := file.Overwrite(contents.String()); err != nil {
+		t.Fatal("failed to write test file: ", err)
+	}
+	checkpoint := ".tests/resumable.checkpoint"
+	_ = os.Remove(checkpoint)
+
+	// the first run crashes on the 35th event, after the checkpoint of the 30th line was saved, without saving
+	// another checkpoint, which the panic simulates.
+	crash := errors.New("crash")
+	reader, err := file.ResumableReader(checkpoint)
+	if err != nil {
+		t.Fatal("failed to open resumable reader: ", err)
+	}
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != crash {
+				t.Fatal("expected the reader to crash, got ", recovered)
+			}
+		}()
+		_ = reader.CheckpointEvery(10).EachLine(streaming.TypedLines(func(e *event, position streaming.Position) error {
+			if int64(e.Id) != position.Line {
+				t.Fatal("expected event ", position.Line, " got ", e.Id)
+			}
+			if e.Id == 35 {
+				panic(crash)
+			}
+			return nil
+		}))
+	}()
+	position, err := reader.Checkpoint()
+	if err != nil || position.Line != 30 || position.Offset != int64(strings.Index(contents.String(), "{\"id\":31}")) {
+		t.Fatal("expected the checkpoint to be at the 30th line, got ", position, err)
+	}
+
+	// the second run gets the events after the checkpoint again, and then fails on the 40th event.
+	var ids []int
+	reader, err = file.ResumableReader(checkpoint)
+	if err != nil {
+		t.Fatal("failed to open resumable reader: ", err)
+	}
+	err = reader.CheckpointEvery(10).EachLine(streaming.TypedLines(func(e *event, position streaming.Position) error {
+		if e.Id == 40 {
+			return crash
+		}
+		ids = append(ids, e.Id)
+		return nil
+	}))
+	if !errors.Is(err, crash) {
+		t.Fatal("expected the failure to be returned, got ", err)
+	}
+	if len(ids) != 9 || ids[0] != 31 || ids[8] != 39 {
+		t.Fatal("expected events 31 to 39 to be redelivered, got ", ids)
+	}
+	position, err = reader.Checkpoint()
+	if err != nil || position.Line != 39 || position.Offset != int64(strings.Index(contents.String(), "{\"id\":40}")) {
+		t.Fatal("expected the checkpoint to be right before the failed line, got ", position, err)
+	}
+
+	ids = nil
+	for run := 0; run < 2; run++ {
+		reader, err = file.ResumableReader(checkpoint)
+		if err != nil {
+			t.Fatal("failed to open resumable reader: ", err)
+		}
+		err = reader.CheckpointEvery(10).EachLine(streaming.TypedLines(func(e *event, position streaming.Position) error {
+			ids = append(ids, e.Id)
+			return nil
+		}))
+		if err != nil {
+			t.Fatal("failed to resume reading: ", err)
+		}
+	}
+	if len(ids) != 61 || ids[0] != 40 || ids[60] != 100 {
+		t.Fatal("expected events 40 to 100 to be read once, got ", ids)
+	}
+	position, err = reader.Checkpoint()
+	if err != nil || position.Line != 100 || position.Offset != int64(contents.Len()) {
+		t.Fatal("expected the checkpoint to be at the end of the file, got ", position, err)
+	}
+
+	// lines appended since the checkpoint are read on their own.
+	if err := file.Write("{\"id\":101}\n"); err != nil {
+		t.Fatal("failed to append to test file: ", err)
+	}
+	ids = nil
+	reader, err = file.ResumableReader(checkpoint)
+	if err != nil {
+		t.Fatal("failed to open resumable reader: ", err)
+	}
+	if err := reader.EachLine(streaming.TypedLines(func(e *event, position streaming.Position) error {
+		ids = append(ids, e.Id)
+		return nil
+	})); err != nil || len(ids) != 1 || ids[0] != 101 {
+		t.Fatal("expected only the appended event to be read, got ", ids, " and ", err)
+	}
+
+	// a file that was replaced since, even with a longer one, is never read from the checkpoint.
+	if err := file.Overwrite(strings.Replace(contents.String(), "{\"id\":100}", "{\"id\":200}", 1) + strings.Repeat("{\"id\":0}\n", 100)); err != nil {
+		t.Fatal("failed to write test file: ", err)
+	}
+	reader, err = file.ResumableReader(checkpoint)
+	if err != nil {
+		t.Fatal("failed to open resumable reader: ", err)
+	}
+	if err := reader.EachLine(func(line []byte, position streaming.Position) error {
+		return nil
+	}); !errors.Is(err, streaming.ErrStaleCheckpoint) {
+		t.Fatal("expected a stale checkpoint, got ", err)
+	}
+
+	if err := file.Overwrite("{\"id\":1}\n"); err != nil {
+		t.Fatal("failed to write test file: ", err)
+	}
+	reader, err = file.ResumableReader(checkpoint)
+	if err != nil {
+		t.Fatal("failed to open resumable reader: ", err)
+	}
+	if err := reader.EachLine(func(line []byte, position streaming.Position) error {
+		return nil
+	}); !errors.Is(err, streaming.ErrStaleCheckpoint) {
+		t.Fatal("expected a stale checkpoint, got ", err)
+	}
 }
 
 func BenchmarkFile_Write(b *testing.B) {
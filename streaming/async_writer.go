@@ -0,0 +1,180 @@
+package streaming
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/ShindouMihou/siopao/paopao"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type FullPolicy uint8
+
+const (
+	// BlockWhenFull makes Write wait until there is room in the queue, this is the default.
+	BlockWhenFull FullPolicy = iota
+	// DropOldest removes the oldest queued write to make room for the new one.
+	DropOldest
+	// DropNewest drops the new write, leaving the queue as-is.
+	DropNewest
+	// FailWhenFull makes Write fail with ErrQueueFull.
+	FailWhenFull
+)
+
+var (
+	// ErrQueueFull is returned by the Write of an AsyncWriter whose queue is full when it uses FailWhenFull.
+	ErrQueueFull = errors.New("queue is full")
+	// ErrWriterEnded is returned when writing into an AsyncWriter that has already ended.
+	ErrWriterEnded = errors.New("writer has ended")
+)
+
+// AsyncWriter is a Writer whose writes are queued and written by a background goroutine, so that Write never waits
+// for the disk, the contents are then flushed once enough bytes are buffered, or every interval. What happens when
+// the queue is full can be configured with WhenFull, while errors of the background goroutine are passed to
+// OnError and returned by End.
+//
+// An AsyncWriter is safe for concurrent use, but it has to be configured before the first Write.
+type AsyncWriter struct {
+	writer   *Writer
+	queue    chan []byte
+	flushes  chan chan error
+	done     chan struct{}
+	start    sync.Once
+	policy   FullPolicy
+	onError  func(err error)
+	interval time.Duration
+	size     int
+
+	mu       sync.RWMutex
+	ended    bool
+	dropped  atomic.Int64
+	failures atomic.Int64
+	err      error
+}
+
+// NewAsyncWriter creates an AsyncWriter over the Writer with a queue of the given amount of writes. The
+// AsyncWriter takes over the Writer, which should no longer be used directly, including AlwaysAppendNewLine, which
+// is applied to each write as usual.
+//
+// By default, the contents are flushed once 64 KiB are buffered, or every second, whichever comes first.
+func NewAsyncWriter(writer *Writer, queue int) *AsyncWriter {
+	if queue < 1 {
+		queue = 1
+	}
+	return &AsyncWriter{
+		writer:   writer,
+		queue:    make(chan []byte, queue),
+		flushes:  make(chan chan error),
+		done:     make(chan struct{}),
+		interval: time.Second,
+		size:     64 << 10,
+	}
+}
+
+// Async creates an AsyncWriter over the Writer, see NewAsyncWriter.
+func (writer *Writer) Async(queue int) *AsyncWriter {
+	return NewAsyncWriter(writer, queue)
+}
+
+// WhenFull will set what Write does when the queue is full, this defaults to BlockWhenFull.
+func (writer *AsyncWriter) WhenFull(policy FullPolicy) *AsyncWriter {
+	writer.policy = policy
+	return writer
+}
+
+// FlushAt will set the AsyncWriter to flush once the given amount of bytes were written since the last flush.
+func (writer *AsyncWriter) FlushAt(bytes int) *AsyncWriter {
+	writer.size = bytes
+	return writer
+}
+
+// FlushEvery will set the AsyncWriter to flush on the given interval, an interval of zero disables this.
+func (writer *AsyncWriter) FlushEvery(interval time.Duration) *AsyncWriter {
+	writer.interval = interval
+	return writer
+}
+
+// OnError will set the function that is called, from the background goroutine, when writing or flushing fails.
+func (writer *AsyncWriter) OnError(fn func(err error)) *AsyncWriter {
+	writer.onError = fn
+	return writer
+}
+
+// Write queues the content to be written, see Writer.Write for how the content is written. The content is marshaled,
+// and readers are read, before Write returns, so the content can be reused right away.
+func (writer *AsyncWriter) Write(t any) error {
+	switch t := t.(type) {
+	case string:
+		return writer.enqueue([]byte(t))
+	case []byte:
+		return writer.enqueue(append([]byte(nil), t...))
+	case *bufio.Reader:
+		return writer.read(t)
+	case bufio.Reader:
+		return writer.read(&t)
+	case io.Reader:
+		return writer.read(t)
+	default:
+		bytes, err := paopao.Marshal(t)
+		if err != nil {
+			return err
+		}
+		return writer.enqueue(bytes)
+	}
+}
+
+// WriteMarshal marshals the content with the given marshaller and queues it, similar to Writer.WriteMarshal.
+func (writer *AsyncWriter) WriteMarshal(marshaller paopao.Marshaller, t any) error {
+	bytes, err := marshaller(t)
+	if err != nil {
+		return err
+	}
+	return writer.enqueue(bytes)
+}
+
+// Dropped gets the amount of writes that were dropped because the queue was full.
+func (writer *AsyncWriter) Dropped() int64 {
+	return writer.dropped.Load()
+}
+
+// Failures gets the amount of times that writing or flushing failed in the background.
+func (writer *AsyncWriter) Failures() int64 {
+	return writer.failures.Load()
+}
+
+// Flush waits until every write queued before it was written and flushed into the file.
+func (writer *AsyncWriter) Flush() error {
+	writer.mu.RLock()
+	defer writer.mu.RUnlock()
+	if writer.ended {
+		return ErrWriterEnded
+	}
+	writer.start.Do(writer.run)
+	reply := make(chan error, 1)
+	writer.flushes <- reply
+	return <-reply
+}
+
+// End writes everything that is still queued, flushes and closes the file, and then returns the first error that
+// happened in the background, if any, along with how many times it failed in total.
+func (writer *AsyncWriter) End() error {
+	writer.mu.Lock()
+	if writer.ended {
+		writer.mu.Unlock()
+		return ErrWriterEnded
+	}
+	writer.ended = true
+	writer.start.Do(writer.run)
+	close(writer.queue)
+	writer.mu.Unlock()
+
+	<-writer.done
+	err := writer.err
+	if failures := writer.failures.Load(); failures > 1 {
+		err = fmt.Errorf("%w (failed %d times in total)", err, failures)
+	}
+	return errors.Join(err, writer.writer.End())
+}
@@ -0,0 +1,112 @@
+package streaming
+
+import (
+	"io"
+	"time"
+)
+
+func (writer *AsyncWriter) read(reader io.Reader) error {
+	bytes, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return writer.enqueue(bytes)
+}
+
+func (writer *AsyncWriter) enqueue(content []byte) error {
+	writer.mu.RLock()
+	defer writer.mu.RUnlock()
+	if writer.ended {
+		return ErrWriterEnded
+	}
+	writer.start.Do(writer.run)
+
+	switch writer.policy {
+	case DropNewest:
+		select {
+		case writer.queue <- content:
+		default:
+			writer.dropped.Add(1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case writer.queue <- content:
+				return nil
+			default:
+			}
+			select {
+			case <-writer.queue:
+				writer.dropped.Add(1)
+			default:
+			}
+		}
+	case FailWhenFull:
+		select {
+		case writer.queue <- content:
+		default:
+			return ErrQueueFull
+		}
+	default:
+		writer.queue <- content
+	}
+	return nil
+}
+
+// run starts the background goroutine that writes the queued contents until the queue is closed by End.
+func (writer *AsyncWriter) run() {
+	go func() {
+		defer close(writer.done)
+		var ticks <-chan time.Time
+		if writer.interval > 0 {
+			ticker := time.NewTicker(writer.interval)
+			defer ticker.Stop()
+			ticks = ticker.C
+		}
+		unflushed := 0
+		flush := func() error {
+			unflushed = 0
+			return writer.fail(writer.writer.writer.Flush())
+		}
+		for {
+			select {
+			case content, ok := <-writer.queue:
+				if !ok {
+					_ = flush()
+					return
+				}
+				if err := writer.fail(writer.writer.write(content)); err == nil {
+					unflushed += len(content)
+				}
+				if writer.size > 0 && unflushed >= writer.size {
+					_ = flush()
+				}
+			case reply := <-writer.flushes:
+				// the writes that were queued before the flush are written first.
+				for pending := len(writer.queue); pending > 0; pending-- {
+					_ = writer.fail(writer.writer.write(<-writer.queue))
+				}
+				reply <- flush()
+			case <-ticks:
+				if unflushed > 0 {
+					_ = flush()
+				}
+			}
+		}
+	}()
+}
+
+// fail counts the error, keeping only the first one since the errors of a bufio.Writer are sticky, and passes it to
+// the error handler, if there is any.
+func (writer *AsyncWriter) fail(err error) error {
+	if err == nil {
+		return nil
+	}
+	if writer.failures.Add(1) == 1 {
+		writer.err = err
+	}
+	if writer.onError != nil {
+		writer.onError(err)
+	}
+	return err
+}
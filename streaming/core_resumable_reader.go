@@ -0,0 +1,183 @@
+package streaming
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"github.com/ShindouMihou/siopao/paopao"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+)
+
+// prefixWindow is the amount of bytes before the offset of a checkpoint that are hashed into it, which is how a file
+// that was replaced since the checkpoint was saved is told apart from the one that was read.
+const prefixWindow = 4 << 10
+
+// checkpoint is what is saved into the checkpoint file, Prefix is the crc32 of the bytes, up to prefixWindow, right
+// before the offset, which is nil for the positions that were given to Commit, as those weren't read.
+type checkpoint struct {
+	Offset int64   `json:"offset"`
+	Line   int64   `json:"line"`
+	Prefix *uint32 `json:"prefix,omitempty"`
+}
+
+func (reader *ResumableReader) eachline(fn ResumableLineReader) error {
+	saved, err := reader.saved()
+	if err != nil {
+		return err
+	}
+	tail, err := reader.skip(saved)
+	if err != nil {
+		return err
+	}
+
+	rd := bufio.NewReaderSize(reader.file, 64<<10)
+	position := Position{Offset: saved.Offset, Line: saved.Line}
+	committed, checkpointed := position, time.Now()
+	stop := func(err error) error {
+		if cerr := reader.commit(&committed, position, tail); cerr != nil {
+			return errors.Join(err, cerr)
+		}
+		return err
+	}
+	for {
+		line, err := rd.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			// lines longer than the buffer are gathered into a single slice.
+			full := append([]byte(nil), line...)
+			for err == bufio.ErrBufferFull {
+				line, err = rd.ReadSlice('\n')
+				full = append(full, line...)
+			}
+			line = full
+		}
+		if err != nil && err != io.EOF {
+			return stop(err)
+		}
+		if len(line) == 0 {
+			return stop(nil)
+		}
+
+		next := Position{Offset: position.Offset + int64(len(line)), Line: position.Line + 1}
+		raw := line
+		line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte{'\n'}), []byte{'\r'})
+		if err := fn(line, Position{Offset: position.Offset, Line: next.Line}); err != nil {
+			return stop(err)
+		}
+		position = next
+		tail = append(tail, raw...)
+		if len(tail) > 2*prefixWindow {
+			tail = append(tail[:0], tail[len(tail)-prefixWindow:]...)
+		}
+
+		due := reader.lines > 0 && position.Line-committed.Line >= reader.lines
+		if !due && reader.interval > 0 && time.Since(checkpointed) >= reader.interval {
+			due = true
+		}
+		if due {
+			if err := reader.commit(&committed, position, tail); err != nil {
+				return err
+			}
+			checkpointed = time.Now()
+		}
+	}
+}
+
+// commit saves the position as the checkpoint when it differs from the last one that was committed, the tail is the
+// bytes that were read right before the position.
+func (reader *ResumableReader) commit(committed *Position, position Position, tail []byte) error {
+	if *committed == position {
+		return nil
+	}
+	if len(tail) > prefixWindow {
+		tail = tail[len(tail)-prefixWindow:]
+	}
+	prefix := crc32.ChecksumIEEE(tail)
+	if err := commit(reader.checkpoint, checkpoint{Offset: position.Offset, Line: position.Line, Prefix: &prefix}); err != nil {
+		return err
+	}
+	*committed = position
+	return nil
+}
+
+// saved reads the checkpoint file, this is the zero checkpoint when there is none.
+func (reader *ResumableReader) saved() (checkpoint, error) {
+	var saved checkpoint
+	bytes, err := os.ReadFile(reader.checkpoint)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return saved, nil
+		}
+		return saved, err
+	}
+	err = paopao.Unmarshal(bytes, &saved)
+	return saved, err
+}
+
+// skip moves the file to the offset of the checkpoint and returns the bytes right before it, failing with
+// ErrStaleCheckpoint when the file is shorter, or when those bytes aren't the ones that were read before.
+func (reader *ResumableReader) skip(saved checkpoint) ([]byte, error) {
+	if saved.Offset == 0 {
+		return nil, nil
+	}
+	window := saved.Offset
+	if window > prefixWindow {
+		window = prefixWindow
+	}
+	if seeker, ok := reader.file.(io.Seeker); ok {
+		size, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, err
+		}
+		if size < saved.Offset {
+			return nil, ErrStaleCheckpoint
+		}
+		if _, err := seeker.Seek(saved.Offset-window, io.SeekStart); err != nil {
+			return nil, err
+		}
+	} else if _, err := io.CopyN(io.Discard, reader.file, saved.Offset-window); err != nil {
+		if err == io.EOF {
+			return nil, ErrStaleCheckpoint
+		}
+		return nil, err
+	}
+
+	tail := make([]byte, window)
+	if _, err := io.ReadFull(reader.file, tail); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrStaleCheckpoint
+		}
+		return nil, err
+	}
+	if saved.Prefix != nil && crc32.ChecksumIEEE(tail) != *saved.Prefix {
+		return nil, ErrStaleCheckpoint
+	}
+	return tail, nil
+}
+
+// commit writes the checkpoint into a temporary file that is synced and then renamed over the checkpoint file.
+func commit(path string, saved checkpoint) error {
+	contents, err := paopao.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	temp := path + ".tmp"
+	f, err := os.OpenFile(temp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(contents); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
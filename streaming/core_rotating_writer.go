@@ -0,0 +1,287 @@
+package streaming
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rotationTime is the format of the time in the names of the files rotated with TimestampNaming.
+const rotationTime = "20060102T150405"
+
+func (writer *RotatingWriter) open() error {
+	if dir := filepath.Dir(writer.path); dir != "." {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(writer.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	writer.writer = NewWriterSize(f, writer.size)
+	writer.written = info.Size()
+	writer.lines = 0
+	writer.opened = time.Now()
+	return nil
+}
+
+func (writer *RotatingWriter) record(content []byte) error {
+	if writer.appendNewLine {
+		content = append(content[:len(content):len(content)], '\n')
+	}
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	if err := writer.prepare(int64(len(content))); err != nil {
+		return err
+	}
+	if err := writer.writer.write(content); err != nil {
+		return err
+	}
+	writer.written += int64(len(content))
+	writer.lines += int64(bytes.Count(content, []byte{'\n'}))
+	return nil
+}
+
+func (writer *RotatingWriter) stream(reader io.Reader) error {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	if err := writer.prepare(0); err != nil {
+		return err
+	}
+	counter := &lineCounter{}
+	n, err := io.Copy(io.MultiWriter(writer.writer.writer, counter), reader)
+	writer.written += n
+	writer.lines += counter.lines
+	if err != nil {
+		return err
+	}
+	if writer.appendNewLine {
+		if err := writer.writer.writer.WriteByte('\n'); err != nil {
+			return err
+		}
+		writer.written++
+		writer.lines++
+	}
+	return nil
+}
+
+// prepare rotates the file when writing the given amount of bytes would cross any of the limits, a file that is
+// still empty is never rotated, so a write bigger than the size limit gets a file of its own.
+func (writer *RotatingWriter) prepare(n int64) error {
+	if writer.written == 0 {
+		return nil
+	}
+	if (writer.maxSize > 0 && writer.written+n > writer.maxSize) ||
+		(writer.maxLines > 0 && writer.lines >= writer.maxLines) ||
+		(writer.interval > 0 && time.Since(writer.opened) >= writer.interval) {
+		return writer.rotate()
+	}
+	return nil
+}
+
+func (writer *RotatingWriter) rotate() error {
+	if err := writer.writer.End(); err != nil {
+		return err
+	}
+	rotated, err := writer.next()
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(writer.path, rotated); err != nil {
+		return errors.Join(err, writer.open())
+	}
+	if err := writer.open(); err != nil {
+		return err
+	}
+
+	writer.pending.Add(1)
+	go func() {
+		defer writer.pending.Done()
+		writer.housekeeping.Lock()
+		defer writer.housekeeping.Unlock()
+		if writer.compress {
+			if err := compress(rotated); err != nil {
+				writer.errs = append(writer.errs, err)
+			}
+		}
+		if err := writer.retain(); err != nil {
+			writer.errs = append(writer.errs, err)
+		}
+	}()
+	return nil
+}
+
+// next gets the path that the current file is rotated into.
+func (writer *RotatingWriter) next() (string, error) {
+	dir, stem, ext := writer.split()
+	if writer.naming == SequenceNaming {
+		segments, err := writer.segments()
+		if err != nil {
+			return "", err
+		}
+		sequence := 1
+		for _, segment := range segments {
+			if segment.sequence >= sequence {
+				sequence = segment.sequence + 1
+			}
+		}
+		return filepath.Join(dir, stem+"."+strconv.Itoa(sequence)+ext), nil
+	}
+
+	name := stem + "-" + time.Now().Format(rotationTime)
+	candidate := name
+	for i := 1; ; i++ {
+		_, err := os.Lstat(filepath.Join(dir, candidate+ext))
+		_, gzErr := os.Lstat(filepath.Join(dir, candidate+ext+".gz"))
+		if errors.Is(err, os.ErrNotExist) && errors.Is(gzErr, os.ErrNotExist) {
+			return filepath.Join(dir, candidate+ext), nil
+		}
+		candidate = name + "-" + strconv.Itoa(i)
+	}
+}
+
+type segment struct {
+	path     string
+	sequence int
+	modTime  time.Time
+}
+
+// segments lists the rotated files of the writer, compressed or not.
+func (writer *RotatingWriter) segments() ([]segment, error) {
+	dir, stem, ext := writer.split()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var segments []segment
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".gz")
+		if entry.IsDir() || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		name = strings.TrimSuffix(name, ext)
+
+		found := segment{path: filepath.Join(dir, entry.Name())}
+		if writer.naming == SequenceNaming {
+			sequence, ok := strings.CutPrefix(name, stem+".")
+			number, err := strconv.Atoi(sequence)
+			if !ok || err != nil || number < 1 {
+				continue
+			}
+			found.sequence = number
+		} else {
+			timestamp, ok := strings.CutPrefix(name, stem+"-")
+			timestamp, _, _ = strings.Cut(timestamp, "-")
+			if _, err := time.Parse(rotationTime, timestamp); !ok || err != nil {
+				continue
+			}
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		found.modTime = info.ModTime()
+		segments = append(segments, found)
+	}
+	return segments, nil
+}
+
+// retain removes the rotated files that are beyond KeepFiles or older than KeepFor.
+func (writer *RotatingWriter) retain() error {
+	if writer.keepFiles <= 0 && writer.keepFor <= 0 {
+		return nil
+	}
+	segments, err := writer.segments()
+	if err != nil {
+		return err
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		if !segments[i].modTime.Equal(segments[j].modTime) {
+			return segments[i].modTime.After(segments[j].modTime)
+		}
+		if segments[i].sequence != segments[j].sequence {
+			return segments[i].sequence > segments[j].sequence
+		}
+		return segments[i].path > segments[j].path
+	})
+
+	var errs []error
+	for i, segment := range segments {
+		if (writer.keepFiles > 0 && i >= writer.keepFiles) ||
+			(writer.keepFor > 0 && time.Since(segment.modTime) > writer.keepFor) {
+			if err := os.Remove(segment.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (writer *RotatingWriter) split() (dir string, stem string, ext string) {
+	dir = filepath.Dir(writer.path)
+	base := filepath.Base(writer.path)
+	ext = filepath.Ext(base)
+	return dir, strings.TrimSuffix(base, ext), ext
+}
+
+// compress compresses the file with gzip into the same path with ".gz" added, then removes the file. The file is
+// written under a temporary name first, so a half-compressed file is never left behind as a segment.
+func compress(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	temp := path + ".gz.tmp"
+	dst, err := os.OpenFile(temp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(temp)
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		return errors.Join(err, dst.Close())
+	}
+	if err := gz.Close(); err != nil {
+		return errors.Join(err, dst.Close())
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	if info, err := src.Stat(); err == nil {
+		_ = os.Chtimes(temp, info.ModTime(), info.ModTime())
+	}
+	if err := os.Rename(temp, path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// lineCounter counts the new lines that are written into it.
+type lineCounter struct {
+	lines int64
+}
+
+func (counter *lineCounter) Write(p []byte) (int, error) {
+	counter.lines += int64(bytes.Count(p, []byte{'\n'}))
+	return len(p), nil
+}
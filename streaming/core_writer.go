@@ -1,15 +1,115 @@
 package streaming
 
 import (
+	"errors"
 	"github.com/ShindouMihou/siopao/internal/buffer"
 	"io"
 )
 
+// wrtbuffer writes the reader into the buffer, rather than the file directly, so that it stays in order with the
+// contents that are still buffered.
 func (writer *Writer) wrtbuffer(buf io.Reader) error {
-	return buffer.Read(buf, 4_096, func(bytes []byte) error {
-		if _, err := writer.file.Write(bytes); err != nil {
+	if err := buffer.Read(buf, 4_096, func(bytes []byte) error {
+		n, err := writer.writer.Write(bytes)
+		writer.progress.Add(int64(n))
+		if err != nil {
 			return err
 		}
 		return nil
-	})
+	}); err != nil {
+		return err
+	}
+	if writer.appendNewLine {
+		if err := writer.writer.WriteByte('\n'); err != nil {
+			return err
+		}
+		writer.progress.Add(1)
+	}
+	return nil
+}
+
+// record is a write that was queued by a Batched Writer, the records form a stack with the newest on top.
+type record struct {
+	content []byte
+	next    *record
+}
+
+// record writes the content as a single record, queueing it instead when the Writer is Batched and another goroutine
+// is currently writing.
+func (writer *Writer) record(content []byte) error {
+	if writer.mu == nil {
+		return writer.write(content)
+	}
+	if writer.batched && !writer.mu.TryLock() {
+		// the content is copied, as the caller is free to reuse it once Write returns.
+		writer.push(append([]byte(nil), content...))
+		if !writer.mu.TryLock() {
+			// the goroutine that holds the Writer writes the record before it lets go, see unlock.
+			return nil
+		}
+		return errors.Join(writer.drain(), writer.unlock())
+	}
+	if !writer.batched {
+		writer.mu.Lock()
+	}
+	err := errors.Join(writer.drain(), writer.write(content))
+	return errors.Join(err, writer.unlock())
+}
+
+// locked runs the function while holding the Writer, when the Writer is Concurrent, after writing every queued
+// record.
+func (writer *Writer) locked(fn func() error) error {
+	if writer.mu == nil {
+		return fn()
+	}
+	writer.mu.Lock()
+	err := errors.Join(writer.drain(), fn())
+	return errors.Join(err, writer.unlock())
+}
+
+// unlock lets go of the Writer, then writes the records that were queued while letting go, as the goroutines that
+// queued them saw the Writer as held and left them to this goroutine.
+func (writer *Writer) unlock() error {
+	var errs []error
+	for {
+		writer.mu.Unlock()
+		if !writer.batched || writer.pending.Load() == nil || !writer.mu.TryLock() {
+			return errors.Join(errs...)
+		}
+		if err := writer.drain(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+}
+
+func (writer *Writer) push(content []byte) {
+	next := &record{content: content}
+	for {
+		next.next = writer.pending.Load()
+		if writer.pending.CompareAndSwap(next.next, next) {
+			return
+		}
+	}
+}
+
+// drain writes every queued record, from the oldest to the newest, the Writer must be held by the caller.
+func (writer *Writer) drain() error {
+	if !writer.batched {
+		return nil
+	}
+	head := writer.pending.Swap(nil)
+	var ordered *record
+	for head != nil {
+		next := head.next
+		head.next = ordered
+		ordered = head
+		head = next
+	}
+	var err error
+	for ; ordered != nil; ordered = ordered.next {
+		if writeErr := writer.write(ordered.content); writeErr != nil && err == nil {
+			err = writeErr
+		}
+	}
+	return err
 }
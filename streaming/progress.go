@@ -0,0 +1,109 @@
+package streaming
+
+import "time"
+
+// Progress is a snapshot of how far an operation is, such as a copy or a write stream.
+type Progress struct {
+	// Done is the amount of bytes that were processed so far.
+	Done int64
+	// Total is the total amount of bytes to process, this is -1 when it is unknown.
+	Total int64
+	// Rate is the average amount of bytes processed per second since the operation started.
+	Rate float64
+	// ETA is the estimated time until the operation completes, this is zero when the Total or the Rate is unknown.
+	ETA time.Duration
+}
+
+// ProgressFunc is called with the Progress of an operation.
+type ProgressFunc func(progress Progress)
+
+// ProgressChannel creates a ProgressFunc that sends the Progress into the channel, any Progress that doesn't fit in
+// the channel is dropped instead of blocking the operation. This doesn't close the channel.
+func ProgressChannel(ch chan<- Progress) ProgressFunc {
+	return func(progress Progress) {
+		select {
+		case ch <- progress:
+		default:
+		}
+	}
+}
+
+// Tracker counts the bytes written into it and reports the Progress to the ProgressFunc at most once every interval,
+// the first write and Finish are always reported. This is an io.Writer, which allows it to be used alongside
+// io.TeeReader and io.MultiWriter. A Tracker is not safe for concurrent use.
+type Tracker struct {
+	fn       ProgressFunc
+	interval time.Duration
+	total    int64
+	done     int64
+	start    int64
+	started  time.Time
+	last     time.Time
+}
+
+// NewTracker creates a Tracker for an operation of total bytes, use -1 when the total is unknown. An interval of zero,
+// or less, reports every write.
+func NewTracker(total int64, interval time.Duration, fn ProgressFunc) *Tracker {
+	return &Tracker{fn: fn, interval: interval, total: total, started: time.Now()}
+}
+
+// From sets the amount of bytes that were already done before the Tracker, such as when resuming an operation, these
+// bytes are not counted towards the Rate.
+func (tracker *Tracker) From(done int64) *Tracker {
+	if tracker == nil {
+		return nil
+	}
+	tracker.done = done
+	tracker.start = done
+	return tracker
+}
+
+// Write counts the length of the bytes, this never fails.
+func (tracker *Tracker) Write(p []byte) (int, error) {
+	tracker.Add(int64(len(p)))
+	return len(p), nil
+}
+
+// Add counts the given amount of bytes and reports the Progress, if the interval has passed since the last report.
+func (tracker *Tracker) Add(n int64) {
+	if tracker == nil {
+		return
+	}
+	tracker.done += n
+	now := time.Now()
+	if !tracker.last.IsZero() && now.Sub(tracker.last) < tracker.interval {
+		return
+	}
+	tracker.report(now)
+}
+
+// Finish reports the final Progress regardless of the interval.
+func (tracker *Tracker) Finish() {
+	if tracker == nil {
+		return
+	}
+	tracker.report(time.Now())
+}
+
+// Progress gets the current Progress of the Tracker.
+func (tracker *Tracker) Progress() Progress {
+	return tracker.progress(time.Now())
+}
+
+func (tracker *Tracker) report(now time.Time) {
+	tracker.last = now
+	if tracker.fn != nil {
+		tracker.fn(tracker.progress(now))
+	}
+}
+
+func (tracker *Tracker) progress(now time.Time) Progress {
+	progress := Progress{Done: tracker.done, Total: tracker.total}
+	if elapsed := now.Sub(tracker.started).Seconds(); elapsed > 0 {
+		progress.Rate = float64(tracker.done-tracker.start) / elapsed
+	}
+	if tracker.total >= 0 && progress.Rate > 0 && tracker.done < tracker.total {
+		progress.ETA = time.Duration(float64(tracker.total-tracker.done) / progress.Rate * float64(time.Second))
+	}
+	return progress
+}
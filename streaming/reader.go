@@ -7,12 +7,13 @@ import (
 )
 
 type Reader struct {
-	file  *os.File
+	file  io.ReadCloser
 	cache *[][]byte
 }
 
-// NewReader creates a streaming reader for the given file.
-func NewReader(file *os.File) *Reader {
+// NewReader creates a streaming reader for the given file, this accepts any io.ReadCloser, such as an *os.File or
+// the files of an io/fs.FS.
+func NewReader(file io.ReadCloser) *Reader {
 	return &Reader{file: file}
 }
 
@@ -39,8 +40,15 @@ func (reader *Reader) EachImmutableLine(fn LineReader) error {
 	return reader.eachline(true, fn)
 }
 
-// File gets the underlying os.File of the Reader.
+// File gets the underlying os.File of the Reader, this is nil when the Reader was not created from an os.File, in which
+// case, use Source instead.
 func (reader *Reader) File() *os.File {
+	f, _ := reader.file.(*os.File)
+	return f
+}
+
+// Source gets the underlying io.ReadCloser of the Reader.
+func (reader *Reader) Source() io.ReadCloser {
 	return reader.file
 }
 
@@ -0,0 +1,119 @@
+package streaming
+
+import (
+	"errors"
+	"github.com/ShindouMihou/siopao/paopao"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrStaleCheckpoint is returned when the checkpoint points past the end of the file, or the contents right before the
+// checkpoint differ from the ones that were read, which means that the file was replaced, or truncated, after the
+// checkpoint was saved.
+var ErrStaleCheckpoint = errors.New("checkpoint does not match the file")
+
+// Position is where a ResumableReader is in the file, Offset is the byte offset and Line is the line number.
+//
+// When given to a ResumableLineReader, this is the position of the line itself, starting from line 1 at offset 0,
+// while a saved checkpoint holds the position right after the last processed line, where reading resumes.
+type Position struct {
+	Offset int64 `json:"offset"`
+	Line   int64 `json:"line"`
+}
+
+type ResumableLineReader func(line []byte, position Position) error
+
+// ResumableReader reads the lines of a file while saving its position to a checkpoint file every so often, so that
+// reading can resume from the last checkpoint, instead of from the beginning, after a crash or a restart.
+//
+// The position is only saved after the function succeeded for every line before it, which gives at-least-once
+// delivery: a line is never skipped, but the lines after the last checkpoint are read again after a crash.
+type ResumableReader struct {
+	file       io.ReadCloser
+	checkpoint string
+	lines      int64
+	interval   time.Duration
+}
+
+// NewResumableReader creates a ResumableReader for the given file that saves its checkpoints into the file at the
+// checkpoint path. The file is skipped to the checkpoint with Seek when it is an io.Seeker, otherwise, the contents
+// up to the checkpoint are read and discarded.
+//
+// By default, a checkpoint is saved every 10,000 lines, or every 5 seconds, whichever comes first, along with when the
+// reading stops.
+func NewResumableReader(file io.ReadCloser, checkpoint string) *ResumableReader {
+	return &ResumableReader{
+		file:       file,
+		checkpoint: checkpoint,
+		lines:      10_000,
+		interval:   5 * time.Second,
+	}
+}
+
+// CheckpointEvery will set the ResumableReader to save a checkpoint after the given amount of lines, zero disables
+// this, saving checkpoints more often means reading less lines again after a crash, but is slower.
+func (reader *ResumableReader) CheckpointEvery(lines int64) *ResumableReader {
+	reader.lines = lines
+	return reader
+}
+
+// CheckpointInterval will set the ResumableReader to save a checkpoint on the given interval, zero disables this.
+func (reader *ResumableReader) CheckpointInterval(interval time.Duration) *ResumableReader {
+	reader.interval = interval
+	return reader
+}
+
+// Checkpoint gets the last saved checkpoint, this is the zero Position when there is none.
+func (reader *ResumableReader) Checkpoint() (Position, error) {
+	saved, err := reader.saved()
+	return Position{Offset: saved.Offset, Line: saved.Line}, err
+}
+
+// Commit saves the given position as the checkpoint, atomically replacing the previous checkpoint, so that a crash
+// in the middle leaves either the previous checkpoint, or the new one. This is done by EachLine on its own, but can
+// be used to rewind to, or skip to, a position.
+//
+// The checkpoints saved by EachLine also hold a hash of the contents right before the position, which detects a
+// file that was replaced since, a position given to Commit wasn't read, therefore, only its offset is checked.
+func (reader *ResumableReader) Commit(position Position) error {
+	return commit(reader.checkpoint, checkpoint{Offset: position.Offset, Line: position.Line})
+}
+
+// Reset removes the checkpoint, which makes the next EachLine start from the beginning of the file.
+func (reader *ResumableReader) Reset() error {
+	if err := os.Remove(reader.checkpoint); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// EachLine reads each line of the file, starting from the checkpoint, and performs the function with the line and
+// its position. The byte array is reused, similar to Reader.EachLine, and the line does not include its line ending.
+//
+// When the function returns an error, the reading stops, the position of the line is saved as the checkpoint, so
+// that the line is read again on the next EachLine, and the error is returned. The file is closed afterward.
+func (reader *ResumableReader) EachLine(fn ResumableLineReader) error {
+	defer reader.Close()
+	return reader.eachline(fn)
+}
+
+// Close will abruptly close the underlying io.Reader, without saving a checkpoint.
+func (reader *ResumableReader) Close() {
+	_ = reader.file.Close()
+}
+
+// TypedLines creates a ResumableLineReader that unmarshals each line into the given type with paopao.Unmarshal
+// before performing the function, empty lines are skipped, which makes it suited for newline-delimited json.
+func TypedLines[T any](fn func(t *T, position Position) error) ResumableLineReader {
+	return func(line []byte, position Position) error {
+		if len(line) == 0 {
+			return nil
+		}
+		var t T
+		if err := paopao.Unmarshal(line, &t); err != nil {
+			return err
+		}
+		return fn(&t, position)
+	}
+}
@@ -0,0 +1,180 @@
+package streaming
+
+import (
+	"bufio"
+	"errors"
+	"github.com/ShindouMihou/siopao/paopao"
+	"io"
+	"sync"
+	"time"
+)
+
+type RotationNaming uint8
+
+const (
+	// TimestampNaming names the rotated files with the time of the rotation, such as "app-20240102T150405.log".
+	TimestampNaming RotationNaming = iota
+	// SequenceNaming names the rotated files with an increasing number, such as "app.1.log", then "app.2.log".
+	SequenceNaming
+)
+
+// RotatingWriter is a Writer that moves the file aside and starts a new one once it reaches a size, an amount of lines
+// or an age, which keeps log files from growing forever. Every write lands whole in a single file, a write is never
+// split across two files, and the file is only rotated in between writes.
+//
+// Rotated files can be compressed with gzip and removed after a while with Compress, KeepFiles and KeepFor, this is
+// done in the background and any error is returned by End. Unlike Writer, a RotatingWriter is safe for concurrent
+// use.
+type RotatingWriter struct {
+	mu     sync.Mutex
+	path   string
+	size   int
+	writer *Writer
+
+	written int64
+	lines   int64
+	opened  time.Time
+
+	maxSize       int64
+	maxLines      int64
+	interval      time.Duration
+	naming        RotationNaming
+	compress      bool
+	keepFiles     int
+	keepFor       time.Duration
+	appendNewLine bool
+
+	housekeeping sync.Mutex
+	pending      sync.WaitGroup
+	errs         []error
+}
+
+// NewRotatingWriter creates a RotatingWriter that appends to the file at the given path, creating the file, and its
+// folders, when needed. The RotatingWriter never rotates until one of RotateAtSize, RotateAtLines or RotateEvery is
+// used, or Rotate is called.
+func NewRotatingWriter(path string) (*RotatingWriter, error) {
+	return NewRotatingWriterSize(path, 4096)
+}
+
+// NewRotatingWriterSize creates a RotatingWriter similar to NewRotatingWriter, but with a given buffer size.
+func NewRotatingWriterSize(path string, size int) (*RotatingWriter, error) {
+	writer := &RotatingWriter{path: path, size: size}
+	if err := writer.open(); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// RotateAtSize will set the RotatingWriter to rotate before a write that would grow the file past the given bytes.
+// A single write that is bigger than the size is still written whole into a file of its own.
+func (writer *RotatingWriter) RotateAtSize(bytes int64) *RotatingWriter {
+	writer.maxSize = bytes
+	return writer
+}
+
+// RotateAtLines will set the RotatingWriter to rotate once the file has the given amount of lines.
+func (writer *RotatingWriter) RotateAtLines(lines int64) *RotatingWriter {
+	writer.maxLines = lines
+	return writer
+}
+
+// RotateEvery will set the RotatingWriter to rotate on the first write after the file has been open for the interval.
+func (writer *RotatingWriter) RotateEvery(interval time.Duration) *RotatingWriter {
+	writer.interval = interval
+	return writer
+}
+
+// NameWith will set how the rotated files are named, this defaults to TimestampNaming.
+func (writer *RotatingWriter) NameWith(naming RotationNaming) *RotatingWriter {
+	writer.naming = naming
+	return writer
+}
+
+// Compress will set the RotatingWriter to compress the rotated files with gzip, which adds ".gz" to their names.
+func (writer *RotatingWriter) Compress() *RotatingWriter {
+	writer.compress = true
+	return writer
+}
+
+// KeepFiles will set the RotatingWriter to only keep the given amount of rotated files, removing the oldest ones.
+func (writer *RotatingWriter) KeepFiles(files int) *RotatingWriter {
+	writer.keepFiles = files
+	return writer
+}
+
+// KeepFor will set the RotatingWriter to remove the rotated files that are older than the given duration.
+func (writer *RotatingWriter) KeepFor(age time.Duration) *RotatingWriter {
+	writer.keepFor = age
+	return writer
+}
+
+// AlwaysAppendNewLine will set the RotatingWriter to always append a new line for each write, the new line is
+// written together with the content, so it always ends up in the same file.
+func (writer *RotatingWriter) AlwaysAppendNewLine() *RotatingWriter {
+	writer.appendNewLine = true
+	return writer
+}
+
+// Path gets the path of the file that is currently written to.
+func (writer *RotatingWriter) Path() string {
+	return writer.path
+}
+
+// Write writes the content into the file, rotating the file beforehand when needed, see Writer.Write for how the
+// content is written.
+func (writer *RotatingWriter) Write(t any) error {
+	switch t := t.(type) {
+	case string:
+		return writer.record([]byte(t))
+	case []byte:
+		return writer.record(t)
+	case *bufio.Reader:
+		return writer.stream(t)
+	case bufio.Reader:
+		return writer.stream(&t)
+	case io.Reader:
+		return writer.stream(t)
+	default:
+		bytes, err := paopao.Marshal(t)
+		if err != nil {
+			return err
+		}
+		return writer.record(bytes)
+	}
+}
+
+// WriteMarshal marshals the content with the given marshaller and writes it, similar to Writer.WriteMarshal.
+func (writer *RotatingWriter) WriteMarshal(marshaller paopao.Marshaller, t any) error {
+	bytes, err := marshaller(t)
+	if err != nil {
+		return err
+	}
+	return writer.record(bytes)
+}
+
+// Flush will flush all the buffered contents into the file.
+func (writer *RotatingWriter) Flush() error {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	return writer.writer.Flush()
+}
+
+// Rotate rotates the file right away, regardless of its size, lines or age.
+func (writer *RotatingWriter) Rotate() error {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	return writer.rotate()
+}
+
+// End flushes the contents into the file and closes it, then waits for the compression and removal of the rotated
+// files to complete, returning any error that happened in the background.
+func (writer *RotatingWriter) End() error {
+	writer.mu.Lock()
+	err := writer.writer.End()
+	writer.mu.Unlock()
+
+	writer.pending.Wait()
+	writer.housekeeping.Lock()
+	defer writer.housekeeping.Unlock()
+	return errors.Join(append([]error{err}, writer.errs...)...)
+}
@@ -4,23 +4,31 @@ import (
 	"bufio"
 	"github.com/ShindouMihou/siopao/paopao"
 	"io"
-	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// Writer is a buffered write stream, it is not safe for concurrent use unless Concurrent, or Batched, is used.
 type Writer struct {
-	file          *os.File
+	file          io.WriteCloser
 	writer        *bufio.Writer
 	appendNewLine bool
+	progress      *Tracker
+
+	mu      *sync.Mutex
+	batched bool
+	pending atomic.Pointer[record]
 }
 
-// NewWriter creates a new Writer from the given os.File, this creates a Writer with a buffer size of
-// 4,096 bytes. If you want to create one with a different buffer size, use the NewWriterSize method instead.
-func NewWriter(file *os.File) *Writer {
+// NewWriter creates a new Writer from the given os.File, or any io.WriteCloser, this creates a Writer with a buffer
+// size of 4,096 bytes. If you want to create one with a different buffer size, use the NewWriterSize method instead.
+func NewWriter(file io.WriteCloser) *Writer {
 	return NewWriterSize(file, 4096)
 }
 
-// NewWriterSize creates a new Writer from the given os.File with a given buffer size.
-func NewWriterSize(file *os.File, size int) *Writer {
+// NewWriterSize creates a new Writer from the given os.File, or any io.WriteCloser, with a given buffer size.
+func NewWriterSize(file io.WriteCloser, size int) *Writer {
 	return &Writer{
 		file:          file,
 		writer:        bufio.NewWriterSize(file, size),
@@ -34,28 +42,58 @@ func (writer *Writer) AlwaysAppendNewLine() *Writer {
 	return writer
 }
 
+// Concurrent will set the Writer to be safe for concurrent use, where each Write, along with its new line when
+// AlwaysAppendNewLine is used, lands in the file as a whole without being interleaved with other writes.
+func (writer *Writer) Concurrent() *Writer {
+	if writer.mu == nil {
+		writer.mu = &sync.Mutex{}
+	}
+	return writer
+}
+
+// Batched works like Concurrent, but a Write that would have to wait for another goroutine queues its content
+// instead, without a lock, and returns right away, the queued writes are then written in order by the goroutine that
+// currently holds the Writer, which gives a much higher throughput when many goroutines write at once.
+//
+// Since a queued write returns before it is written, its error is returned by a later Write, Flush or End instead.
+// Writes of an io.Reader are never queued.
+func (writer *Writer) Batched() *Writer {
+	writer.batched = true
+	return writer.Concurrent()
+}
+
+// OnProgress will set the Writer to report the amount of bytes written to the function, at most once every interval.
+// The Total of the Progress is unknown for a Writer, therefore it is always -1 and there is no ETA.
+func (writer *Writer) OnProgress(fn ProgressFunc, interval time.Duration) *Writer {
+	writer.progress = NewTracker(-1, interval, fn)
+	return writer
+}
+
 // Write writes the content into the file, note that this does not append a new line for each write
 // unless the Writer uses AlwaysAppendNewLine. This marshals anything other than string, bufio.Reader and byte array into the
 // paopao.Marshal which is Json by default.
+//
+// An io.Reader, or bufio.Reader, is written through the buffer like any other content, which keeps it in order with
+// the writes that are still buffered and appends the new line of AlwaysAppendNewLine after it.
 func (writer *Writer) Write(t any) error {
 	switch t.(type) {
 	case string:
-		return writer.write([]byte(t.(string)))
+		return writer.record([]byte(t.(string)))
 	case []byte:
-		return writer.write(t.([]byte))
+		return writer.record(t.([]byte))
 	case *bufio.Reader:
-		return writer.wrtbuffer(t.(*bufio.Reader))
+		return writer.locked(func() error { return writer.wrtbuffer(t.(*bufio.Reader)) })
 	case bufio.Reader:
 		buffer := t.(bufio.Reader)
-		return writer.wrtbuffer(&buffer)
+		return writer.locked(func() error { return writer.wrtbuffer(&buffer) })
 	case io.Reader:
-		return writer.wrtbuffer(t.(io.Reader))
+		return writer.locked(func() error { return writer.wrtbuffer(t.(io.Reader)) })
 	default:
 		bytes, err := paopao.Marshal(t)
 		if err != nil {
 			return err
 		}
-		return writer.write(bytes)
+		return writer.record(bytes)
 	}
 }
 
@@ -66,13 +104,13 @@ func (writer *Writer) WriteMarshal(marshaller paopao.Marshaller, t any) error {
 	if err != nil {
 		return err
 	}
-	return writer.write(bytes)
+	return writer.record(bytes)
 }
 
 // Flush will flush all the buffered contents into the file. It is recommended to use this only when you want
 // to push the contents of the file immediately, otherwise use End instead to flush and close the Writer.
 func (writer *Writer) Flush() error {
-	return writer.writer.Flush()
+	return writer.locked(writer.writer.Flush)
 }
 
 // Close will abruptly close the underlying io.Writer of the Writer. IT IS NOT RECOMMENDED TO USE THIS, PLEASE USE
@@ -84,21 +122,32 @@ func (writer *Writer) Close() {
 // End flushes the contents into the file before closing the underlying io.Writer.
 func (writer *Writer) End() error {
 	defer writer.Close()
-	return writer.Flush()
+	return writer.locked(func() error {
+		defer writer.progress.Finish()
+		return writer.writer.Flush()
+	})
 }
 
 // Reset discards any unflushed buffered data, clears any error, and resets buffer to write its output to File.
 // i.e. whatever the heck bufio.Writer's Reset method does.
 func (writer *Writer) Reset() {
-	writer.writer.Reset(writer.file)
+	_ = writer.locked(func() error {
+		writer.writer.Reset(writer.file)
+		return nil
+	})
 }
 
 func (writer *Writer) write(t []byte) error {
-	if _, err := writer.writer.Write(t); err != nil {
+	n, err := writer.writer.Write(t)
+	writer.progress.Add(int64(n))
+	if err != nil {
 		return err
 	}
 	if writer.appendNewLine {
-		return writer.write([]byte{'\n'})
+		if err := writer.writer.WriteByte('\n'); err != nil {
+			return err
+		}
+		writer.progress.Add(1)
 	}
 	return nil
 }
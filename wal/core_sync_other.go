@@ -0,0 +1,15 @@
+//go:build !windows
+
+package wal
+
+import "os"
+
+// syncDir syncs the directory, which makes the creation, and removal, of the files inside it durable.
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
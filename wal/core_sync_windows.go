@@ -0,0 +1,8 @@
+//go:build windows
+
+package wal
+
+// syncDir does nothing on Windows, where a directory cannot be opened for syncing.
+func syncDir(dir string) error {
+	return nil
+}
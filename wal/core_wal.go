@@ -0,0 +1,218 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/ShindouMihou/siopao/streaming"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// a record is made of a header of its length, its checksum and its sequence number, followed by its data, the
+// checksum covers the sequence number and the data.
+const (
+	headerSize    = 16
+	maxRecordSize = 1 << 30
+	extension     = ".wal"
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+func encode(sequence uint64, data []byte) []byte {
+	record := make([]byte, headerSize+len(data))
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(data)))
+	binary.LittleEndian.PutUint64(record[8:16], sequence)
+	copy(record[headerSize:], data)
+	binary.LittleEndian.PutUint32(record[4:8], crc32.Checksum(record[8:], castagnoli))
+	return record
+}
+
+func (log *Log) path(base uint64) string {
+	return filepath.Join(log.dir, fmt.Sprintf("%020d%s", base, extension))
+}
+
+// recover finds the segments of the Log, cuts off the torn tail of the newest segment, if any, and opens it.
+func (log *Log) recover() error {
+	if err := os.MkdirAll(log.dir, os.ModePerm); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(log.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), extension)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		base, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		log.segments = append(log.segments, base)
+	}
+	if len(log.segments) == 0 {
+		log.segments = []uint64{1}
+	}
+
+	newest := log.segments[len(log.segments)-1]
+	path := log.path(newest)
+	offset, next, err := scan(path, newest, nil)
+	torn := errors.Is(err, ErrCorrupt)
+	if err != nil && !torn && !os.IsNotExist(err) {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if err := syncDir(log.dir); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if torn {
+		if err := file.Truncate(offset); err != nil {
+			_ = file.Close()
+			return err
+		}
+		if err := file.Sync(); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	log.open(file, offset)
+	log.next = next
+	return nil
+}
+
+func (log *Log) open(file *os.File, size int64) {
+	log.file = file
+	log.writer = streaming.NewWriterSize(file, 64<<10)
+	log.size = size
+}
+
+// rotate syncs and closes the current segment, then starts a new segment from the next sequence number.
+func (log *Log) rotate() error {
+	if err := log.sync(); err != nil {
+		return err
+	}
+	if err := log.file.Close(); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(log.path(log.next), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	// the new segment is only durable once the directory that lists it was synced as well.
+	if err := syncDir(log.dir); err != nil {
+		_ = file.Close()
+		return err
+	}
+	log.segments = append(log.segments, log.next)
+	log.open(file, 0)
+	return nil
+}
+
+func (log *Log) sync() error {
+	if err := log.writer.Flush(); err != nil {
+		return err
+	}
+	if err := log.file.Sync(); err != nil {
+		return err
+	}
+	log.dirty = false
+	return nil
+}
+
+// failure returns the error of the last background sync, if any, and clears it.
+func (log *Log) failure() error {
+	err := log.err
+	log.err = nil
+	return err
+}
+
+// background syncs the records that were appended since the last sync on every interval of SyncInterval, until
+// the Log is closed.
+func (log *Log) background() {
+	defer close(log.stopped)
+	for {
+		log.mu.Lock()
+		interval := log.interval
+		log.mu.Unlock()
+		if interval <= 0 {
+			interval = 100 * time.Millisecond
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-log.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		log.mu.Lock()
+		if log.policy == SyncInterval && log.dirty && log.err == nil {
+			log.err = log.sync()
+		}
+		log.mu.Unlock()
+	}
+}
+
+// scan reads the records of the segment in order, calling the function, if any, with each of them. This returns the
+// offset right after the last intact record and the sequence number that comes after it, along with ErrCorrupt when
+// a record is incomplete, fails its checksum or is out of order.
+func scan(path string, base uint64, fn func(entry Entry) error) (int64, uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, base, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, 64<<10)
+	header := make([]byte, headerSize)
+	offset, expected := int64(0), base
+	corrupt := func() error {
+		return fmt.Errorf("%w in %s at offset %d", ErrCorrupt, filepath.Base(path), offset)
+	}
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err == io.EOF {
+				return offset, expected, nil
+			}
+			if err == io.ErrUnexpectedEOF {
+				return offset, expected, corrupt()
+			}
+			return offset, expected, err
+		}
+		length := binary.LittleEndian.Uint32(header[0:4])
+		if length > maxRecordSize || binary.LittleEndian.Uint64(header[8:16]) != expected {
+			return offset, expected, corrupt()
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return offset, expected, corrupt()
+			}
+			return offset, expected, err
+		}
+		checksum := crc32.Update(crc32.Checksum(header[8:16], castagnoli), castagnoli, data)
+		if checksum != binary.LittleEndian.Uint32(header[4:8]) {
+			return offset, expected, corrupt()
+		}
+		if fn != nil {
+			if err := fn(Entry{Sequence: expected, Data: data}); err != nil {
+				return offset, expected, err
+			}
+		}
+		offset += int64(headerSize) + int64(length)
+		expected++
+	}
+}
@@ -0,0 +1,281 @@
+// Package wal provides a write-ahead log, a segmented append-only log of records, each record is length-prefixed
+// and checksummed with CRC-32C so that a write torn by a crash is detected, and cut off, when the log is opened again.
+package wal
+
+import (
+	"errors"
+	"github.com/ShindouMihou/siopao/streaming"
+	"os"
+	"sync"
+	"time"
+)
+
+type SyncPolicy uint8
+
+const (
+	// SyncInterval flushes and syncs the log to the disk from the background on every interval of SyncEvery, when
+	// records were appended since the last sync, this is the default, and loses at most an interval of records on a
+	// crash of the machine. A failed background sync is returned by the next Append, Sync or Close.
+	SyncInterval SyncPolicy = iota
+	// SyncAlways flushes and syncs the log to the disk on every Append, this is the safest, but also the slowest.
+	SyncAlways
+	// SyncNever leaves syncing to the operating system, records are only guaranteed to be on the disk after Sync or
+	// Close, and records still in the buffer are lost when the process crashes.
+	SyncNever
+)
+
+var (
+	// ErrCorrupt is returned when a record of a segment, other than the tail of the newest one, is damaged.
+	ErrCorrupt = errors.New("wal: corrupt record")
+	// ErrClosed is returned when using a Log that was already closed.
+	ErrClosed = errors.New("wal: log is closed")
+	// ErrTooLarge is returned when appending a record larger than the maximum record size of 1 GiB.
+	ErrTooLarge = errors.New("wal: record is too large")
+
+	errStop = errors.New("wal: stop")
+)
+
+// Log is a write-ahead log stored as a directory of segments, each segment is named after the sequence number of its
+// first record, and a new segment is started once the current one reaches the segment size, which allows old records
+// to be removed with Compact once they are no longer needed.
+//
+// Every record is given a sequence number, starting from 1 and increasing by one on each Append. A Log is safe for
+// concurrent use.
+type Log struct {
+	mu       sync.Mutex
+	dir      string
+	segments []uint64
+	next     uint64
+
+	file    *os.File
+	writer  *streaming.Writer
+	size    int64
+	dirty   bool
+	err     error
+	closed  bool
+	stop    chan struct{}
+	stopped chan struct{}
+
+	segmentSize int64
+	policy      SyncPolicy
+	interval    time.Duration
+}
+
+// Entry is a record of the Log, as given to the function of Replay.
+type Entry struct {
+	Sequence uint64
+	Data     []byte
+}
+
+// Open opens the write-ahead log in the directory, creating the directory if needed. When the newest segment ends
+// with a record that is incomplete, or fails its checksum, from a write that was torn by a crash, the segment is
+// truncated right before that record, and appending continues from there.
+//
+// By default, segments are 64 MiB and the log is synced every 100 milliseconds from the background, see SegmentSize
+// and SyncWith, the Log has to be closed with Close to stop the background sync.
+func Open(dir string) (*Log, error) {
+	log := &Log{
+		dir:         dir,
+		segmentSize: 64 << 20,
+		policy:      SyncInterval,
+		interval:    100 * time.Millisecond,
+		stop:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	if err := log.recover(); err != nil {
+		return nil, err
+	}
+	go log.background()
+	return log, nil
+}
+
+// SegmentSize will set the size in bytes after which a new segment is started, a record is never split across
+// segments, therefore a segment can be larger than this when it holds a single large record.
+func (log *Log) SegmentSize(bytes int64) *Log {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.segmentSize = bytes
+	return log
+}
+
+// SyncWith will set when the records are synced to the disk, this defaults to SyncInterval.
+func (log *Log) SyncWith(policy SyncPolicy) *Log {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.policy = policy
+	return log
+}
+
+// SyncEvery will set the interval of SyncInterval, this defaults to 100 milliseconds.
+func (log *Log) SyncEvery(interval time.Duration) *Log {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.interval = interval
+	return log
+}
+
+// Dir gets the directory of the Log.
+func (log *Log) Dir() string {
+	return log.dir
+}
+
+// First gets the sequence number of the oldest record that is still in the Log, which changes after Compact.
+func (log *Log) First() uint64 {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	return log.segments[0]
+}
+
+// Last gets the sequence number of the newest record of the Log, or zero when nothing was ever appended.
+func (log *Log) Last() uint64 {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	return log.next - 1
+}
+
+// Append appends the record to the Log and returns its sequence number, whether the record is on the disk once this
+// returns depends on the SyncPolicy.
+func (log *Log) Append(data []byte) (uint64, error) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if log.closed {
+		return 0, ErrClosed
+	}
+	if err := log.failure(); err != nil {
+		return 0, err
+	}
+	if len(data) > maxRecordSize {
+		return 0, ErrTooLarge
+	}
+	record := encode(log.next, data)
+	if log.size > 0 && log.size+int64(len(record)) > log.segmentSize {
+		if err := log.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	if err := log.writer.Write(record); err != nil {
+		return 0, err
+	}
+	log.size += int64(len(record))
+	log.dirty = true
+	sequence := log.next
+	log.next++
+
+	if log.policy == SyncAlways {
+		return sequence, log.sync()
+	}
+	return sequence, nil
+}
+
+// Replay calls the function with every record from the given sequence number onwards, in order, stopping at the
+// first error returned by the function. Records appended while replaying are not included, and when the records
+// before the sequence number were already compacted away, this starts from the oldest record that is left.
+//
+// A damaged record fails the replay with ErrCorrupt, appending to the Log from the function is allowed.
+func (log *Log) Replay(from uint64, fn func(entry Entry) error) error {
+	log.mu.Lock()
+	if log.closed {
+		log.mu.Unlock()
+		return ErrClosed
+	}
+	if err := log.writer.Flush(); err != nil {
+		log.mu.Unlock()
+		return err
+	}
+	segments := append([]uint64(nil), log.segments...)
+	end := log.next
+	log.mu.Unlock()
+	if from >= end {
+		return nil
+	}
+
+	start := 0
+	for i, base := range segments {
+		if base <= from {
+			start = i
+		}
+	}
+	for _, base := range segments[start:] {
+		if base >= end {
+			break
+		}
+		_, _, err := scan(log.path(base), base, func(entry Entry) error {
+			// records after the end may still be half-written by a concurrent Append.
+			if entry.Sequence >= end {
+				return errStop
+			}
+			if entry.Sequence < from {
+				return nil
+			}
+			if err := fn(entry); err != nil {
+				return err
+			}
+			if entry.Sequence+1 >= end {
+				return errStop
+			}
+			return nil
+		})
+		if err == errStop {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compact removes every segment whose records all come before the given sequence number, which is usually the
+// sequence number of the oldest record that is still needed, such as the first record after a snapshot. The newest
+// segment is never removed, therefore the Log can keep records older than the sequence number.
+func (log *Log) Compact(before uint64) error {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if log.closed {
+		return ErrClosed
+	}
+	removed := 0
+	defer func() {
+		log.segments = log.segments[removed:]
+	}()
+	for removed+1 < len(log.segments) && log.segments[removed+1] <= before {
+		if err := os.Remove(log.path(log.segments[removed])); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		removed++
+	}
+	if removed == 0 {
+		return nil
+	}
+	return syncDir(log.dir)
+}
+
+// Sync flushes the buffered records and syncs the newest segment to the disk.
+func (log *Log) Sync() error {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if log.closed {
+		return ErrClosed
+	}
+	if err := log.failure(); err != nil {
+		return err
+	}
+	return log.sync()
+}
+
+// Close syncs the records to the disk and closes the Log.
+func (log *Log) Close() error {
+	log.mu.Lock()
+	if log.closed {
+		log.mu.Unlock()
+		return ErrClosed
+	}
+	log.closed = true
+	log.mu.Unlock()
+
+	close(log.stop)
+	<-log.stopped
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	return errors.Join(log.failure(), log.sync(), log.file.Close())
+}
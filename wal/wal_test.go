@@ -0,0 +1,231 @@
+package wal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fresh(t *testing.T, dir string) *Log {
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal("failed to clean test directory: ", err)
+	}
+	log, err := Open(dir)
+	if err != nil {
+		t.Fatal("failed to open log: ", err)
+	}
+	return log
+}
+
+func count(t *testing.T, log *Log, from uint64) []uint64 {
+	var sequences []uint64
+	err := log.Replay(from, func(entry Entry) error {
+		if string(entry.Data) != "record-"+strconv.FormatUint(entry.Sequence, 10) {
+			return fmt.Errorf("unexpected data %q for record %d", entry.Data, entry.Sequence)
+		}
+		sequences = append(sequences, entry.Sequence)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("failed to replay log: ", err)
+	}
+	return sequences
+}
+
+func appendRecords(t *testing.T, log *Log, amount int) {
+	for i := 0; i < amount; i++ {
+		sequence, err := log.Append([]byte("record-" + strconv.FormatUint(log.Last()+1, 10)))
+		if err != nil {
+			t.Fatal("failed to append to log: ", err)
+		}
+		if sequence != log.Last() {
+			t.Fatal("expected append to return the last sequence, got ", sequence, " instead of ", log.Last())
+		}
+	}
+}
+
+func TestLog_AppendAndReplay(t *testing.T) {
+	log := fresh(t, ".tests/append")
+	log.SyncWith(SyncAlways)
+	appendRecords(t, log, 100)
+
+	if sequences := count(t, log, 50); len(sequences) != 51 || sequences[0] != 50 || sequences[50] != 100 {
+		t.Fatal("expected records 50 to 100 to be replayed, got ", sequences)
+	}
+	if err := log.Close(); err != nil {
+		t.Fatal("failed to close log: ", err)
+	}
+	if _, err := log.Append([]byte("closed")); !errors.Is(err, ErrClosed) {
+		t.Fatal("expected appending to a closed log to fail, got ", err)
+	}
+
+	log, err := Open(".tests/append")
+	if err != nil {
+		t.Fatal("failed to reopen log: ", err)
+	}
+	defer log.Close()
+	if log.Last() != 100 {
+		t.Fatal("expected the reopened log to end at 100, got ", log.Last())
+	}
+	appendRecords(t, log, 1)
+	if sequences := count(t, log, 0); len(sequences) != 101 {
+		t.Fatal("expected 101 records to be replayed, got ", len(sequences))
+	}
+}
+
+func TestLog_ReplayPastEnd(t *testing.T) {
+	log := fresh(t, ".tests/past")
+	defer log.Close()
+	log.SyncWith(SyncAlways)
+	appendRecords(t, log, 10)
+
+	// records that a concurrent Append is still writing, which the Log doesn't count yet.
+	file, err := os.OpenFile(log.path(1), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal("failed to open segment: ", err)
+	}
+	if _, err := file.Write(append(encode(11, []byte("record-11")), encode(12, []byte("record-12"))[:20]...)); err != nil {
+		t.Fatal("failed to write pending records: ", err)
+	}
+	_ = file.Close()
+
+	if sequences := count(t, log, log.Last()+1); len(sequences) != 0 {
+		t.Fatal("expected nothing to be replayed after the last record, got ", sequences)
+	}
+	if sequences := count(t, log, 5); len(sequences) != 6 || sequences[5] != 10 {
+		t.Fatal("expected records 5 to 10 to be replayed, got ", sequences)
+	}
+}
+
+func TestLog_TornTail(t *testing.T) {
+	log := fresh(t, ".tests/torn")
+	appendRecords(t, log, 10)
+	if err := log.Close(); err != nil {
+		t.Fatal("failed to close log: ", err)
+	}
+	path := log.path(1)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal("failed to stat segment: ", err)
+	}
+
+	// a crash halfway through writing the eleventh record.
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal("failed to open segment: ", err)
+	}
+	if _, err := file.Write(encode(11, []byte("record-11"))[:20]); err != nil {
+		t.Fatal("failed to write torn record: ", err)
+	}
+	_ = file.Close()
+
+	log, err = Open(".tests/torn")
+	if err != nil {
+		t.Fatal("failed to reopen log: ", err)
+	}
+	if log.Last() != 10 {
+		t.Fatal("expected the torn record to be cut off, got last record ", log.Last())
+	}
+	if truncated, err := os.Stat(path); err != nil || truncated.Size() != info.Size() {
+		t.Fatal("expected the segment to be truncated back to ", info.Size(), " bytes, got ", truncated, err)
+	}
+	appendRecords(t, log, 1)
+	if sequences := count(t, log, 0); len(sequences) != 11 {
+		t.Fatal("expected 11 records to be replayed, got ", len(sequences))
+	}
+	if err := log.Close(); err != nil {
+		t.Fatal("failed to close log: ", err)
+	}
+
+	// a record whose checksum no longer matches is cut off as well.
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal("failed to read segment: ", err)
+	}
+	bytes[len(bytes)-1] ^= 0xFF
+	if err := os.WriteFile(path, bytes, 0644); err != nil {
+		t.Fatal("failed to corrupt segment: ", err)
+	}
+	log, err = Open(".tests/torn")
+	if err != nil {
+		t.Fatal("failed to reopen log: ", err)
+	}
+	defer log.Close()
+	if log.Last() != 10 {
+		t.Fatal("expected the corrupt record to be cut off, got last record ", log.Last())
+	}
+}
+
+func TestLog_SegmentsAndCompaction(t *testing.T) {
+	log := fresh(t, ".tests/segments")
+	defer log.Close()
+	// each record is 25 or 26 bytes, so two of them fit in a segment.
+	log.SegmentSize(60)
+	appendRecords(t, log, 50)
+
+	entries, err := os.ReadDir(".tests/segments")
+	if err != nil {
+		t.Fatal("failed to read log directory: ", err)
+	}
+	if len(entries) != 25 {
+		t.Fatal("expected 25 segments, got ", len(entries))
+	}
+
+	if err := log.Compact(30); err != nil {
+		t.Fatal("failed to compact log: ", err)
+	}
+	if log.First() != 29 {
+		t.Fatal("expected the oldest record after compaction to be 29, got ", log.First())
+	}
+	if sequences := count(t, log, 0); len(sequences) != 22 || sequences[0] != 29 {
+		t.Fatal("expected records 29 to 50 to be replayed, got ", sequences)
+	}
+	if sequences := count(t, log, 44); len(sequences) != 7 || sequences[0] != 44 {
+		t.Fatal("expected records 44 to 50 to be replayed, got ", sequences)
+	}
+
+	// damage in a segment other than the newest is reported instead of cut off.
+	path := log.path(31)
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal("failed to read segment: ", err)
+	}
+	bytes[headerSize] ^= 0xFF
+	if err := os.WriteFile(path, bytes, 0644); err != nil {
+		t.Fatal("failed to corrupt segment: ", err)
+	}
+	err = log.Replay(0, func(entry Entry) error {
+		return nil
+	})
+	if !errors.Is(err, ErrCorrupt) || !strings.Contains(err.Error(), filepath.Base(path)) {
+		t.Fatal("expected replay to fail with a corrupt record, got ", err)
+	}
+}
+
+func TestLog_SyncInterval(t *testing.T) {
+	log := fresh(t, ".tests/interval")
+	defer log.Close()
+	log.SyncEvery(10 * time.Millisecond)
+	appendRecords(t, log, 1)
+
+	// the record is flushed from the buffer by the background sync, without another Append, Sync or Close.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		info, err := os.Stat(log.path(1))
+		if err != nil {
+			t.Fatal("failed to stat segment: ", err)
+		}
+		if info.Size() > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the record to be synced in the background")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}